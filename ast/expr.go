@@ -4,9 +4,11 @@ import "github.com/taki-mekhalfa/golox/token"
 
 type Expr interface {
 	Accept(VisitorExpr) interface{}
+	Position() token.Span
 }
 
 type Binary struct {
+	Pos
 	Left     Expr
 	Operator token.Token
 	Right    Expr
@@ -17,6 +19,7 @@ func (b *Binary) Accept(v VisitorExpr) interface{} {
 }
 
 type Grouping struct {
+	Pos
 	Expr Expr
 }
 
@@ -25,6 +28,7 @@ func (g *Grouping) Accept(v VisitorExpr) interface{} {
 }
 
 type Literal struct {
+	Pos
 	Value interface{}
 }
 
@@ -33,6 +37,7 @@ func (l *Literal) Accept(v VisitorExpr) interface{} {
 }
 
 type Unary struct {
+	Pos
 	Operator token.Token
 	Expr     Expr
 }
@@ -42,6 +47,7 @@ func (u *Unary) Accept(v VisitorExpr) interface{} {
 }
 
 type Var struct {
+	Pos
 	Token token.Token
 }
 
@@ -50,6 +56,7 @@ func (var_ *Var) Accept(v VisitorExpr) interface{} {
 }
 
 type Assign struct {
+	Pos
 	Identifier token.Token
 	Value      Expr
 }
@@ -59,6 +66,7 @@ func (a *Assign) Accept(v VisitorExpr) interface{} {
 }
 
 type Logical struct {
+	Pos
 	Operator token.Token
 	Left     Expr
 	Right    Expr
@@ -69,6 +77,7 @@ func (l *Logical) Accept(v VisitorExpr) interface{} {
 }
 
 type Call struct {
+	Pos
 	Callee        Expr
 	ClosingParent token.Token
 	Args          []Expr
@@ -79,6 +88,7 @@ func (c *Call) Accept(v VisitorExpr) interface{} {
 }
 
 type Get struct {
+	Pos
 	Object   Expr
 	Property token.Token
 }
@@ -88,6 +98,7 @@ func (g *Get) Accept(v VisitorExpr) interface{} {
 }
 
 type Set struct {
+	Pos
 	Object   Expr
 	Property token.Token
 	Value    Expr
@@ -98,9 +109,88 @@ func (s *Set) Accept(v VisitorExpr) interface{} {
 }
 
 type This struct {
+	Pos
 	Keyword token.Token
 }
 
 func (this *This) Accept(v VisitorExpr) interface{} {
 	return v.VisitThis(this)
 }
+
+type Super struct {
+	Pos
+	Keyword token.Token
+	Method  token.Token
+}
+
+func (s *Super) Accept(v VisitorExpr) interface{} {
+	return v.VisitSuper(s)
+}
+
+type List struct {
+	Pos
+	Bracket  token.Token
+	Elements []Expr
+}
+
+func (l *List) Accept(v VisitorExpr) interface{} {
+	return v.VisitList(l)
+}
+
+type Index struct {
+	Pos
+	Object  Expr
+	Bracket token.Token
+	Index   Expr
+}
+
+func (i *Index) Accept(v VisitorExpr) interface{} {
+	return v.VisitIndex(i)
+}
+
+type Slice struct {
+	Pos
+	Object  Expr
+	Bracket token.Token
+	// Start and End are nil when omitted
+	Start Expr
+	End   Expr
+}
+
+func (s *Slice) Accept(v VisitorExpr) interface{} {
+	return v.VisitSlice(s)
+}
+
+type SetIndex struct {
+	Pos
+	Object  Expr
+	Bracket token.Token
+	Index   Expr
+	Value   Expr
+}
+
+func (s *SetIndex) Accept(v VisitorExpr) interface{} {
+	return v.VisitSetIndex(s)
+}
+
+type Map struct {
+	Pos
+	Brace  token.Token
+	Keys   []Expr
+	Values []Expr
+}
+
+func (m *Map) Accept(v VisitorExpr) interface{} {
+	return v.VisitMap(m)
+}
+
+// Lambda is an anonymous function expression,
+// the 'fun' keyword stands for the name of its Function.
+type Lambda struct {
+	Pos
+	Function *Function
+}
+
+func (l *Lambda) Accept(v VisitorExpr) interface{} {
+	return v.VisitLambda(l)
+}
@@ -0,0 +1,14 @@
+package ast
+
+import "github.com/taki-mekhalfa/golox/token"
+
+// Pos is embedded in every node to record
+// the span of source it was parsed from
+type Pos struct {
+	Span token.Span
+}
+
+// Position returns the span of source the node was parsed from
+func (p Pos) Position() token.Span {
+	return p.Span
+}
@@ -6,9 +6,11 @@ import (
 
 type Stmt interface {
 	Accept(VisitorStmt) interface{}
+	Position() token.Span
 }
 
 type Print struct {
+	Pos
 	Expr Expr
 }
 
@@ -17,6 +19,7 @@ func (p *Print) Accept(v VisitorStmt) interface{} {
 }
 
 type ExprStmt struct {
+	Pos
 	Expr Expr
 }
 
@@ -25,6 +28,7 @@ func (es *ExprStmt) Accept(v VisitorStmt) interface{} {
 }
 
 type VarStmt struct {
+	Pos
 	Name        string
 	Initializer Expr
 	Token       token.Token
@@ -35,6 +39,7 @@ func (var_ *VarStmt) Accept(v VisitorStmt) interface{} {
 }
 
 type Block struct {
+	Pos
 	Content []Stmt
 }
 
@@ -43,6 +48,7 @@ func (b *Block) Accept(v VisitorStmt) interface{} {
 }
 
 type If struct {
+	Pos
 	Condition Expr
 	Then      Stmt
 	Else      Stmt
@@ -53,8 +59,22 @@ func (if_ *If) Accept(v VisitorStmt) interface{} {
 }
 
 type While struct {
+	Pos
 	Condition Expr
 	Body      Stmt
+	// Increment is set for desugared for loops, it is kept
+	// apart from the body so that it still runs on 'continue'
+	Increment Expr
+	// For holds the clauses of the for statement the loop was
+	// desugared from as they were written, nil for while loops
+	For *ForClauses
+}
+
+// ForClauses are the clauses of a for statement, the initializer is run
+// by the block enclosing the loop, both are nil when they are omitted.
+type ForClauses struct {
+	Initializer Stmt
+	Condition   Expr
 }
 
 func (while *While) Accept(v VisitorStmt) interface{} {
@@ -62,6 +82,7 @@ func (while *While) Accept(v VisitorStmt) interface{} {
 }
 
 type Function struct {
+	Pos
 	Name   token.Token
 	Params []token.Token
 	Body   []Stmt
@@ -72,6 +93,7 @@ func (f *Function) Accept(v VisitorStmt) interface{} {
 }
 
 type Return struct {
+	Pos
 	Value Expr
 	token.Token
 }
@@ -81,10 +103,68 @@ func (r *Return) Accept(v VisitorStmt) interface{} {
 }
 
 type Class struct {
-	Name    token.Token
-	Methods []*Function
+	Pos
+	Name       token.Token
+	Superclass *Var
+	Methods    []*Function
 }
 
 func (c *Class) Accept(v VisitorStmt) interface{} {
 	return v.VisitClass(c)
 }
+
+type Break struct {
+	Pos
+	Keyword token.Token
+}
+
+func (b *Break) Accept(v VisitorStmt) interface{} {
+	return v.VisitBreak(b)
+}
+
+type Continue struct {
+	Pos
+	Keyword token.Token
+}
+
+func (c *Continue) Accept(v VisitorStmt) interface{} {
+	return v.VisitContinue(c)
+}
+
+type Throw struct {
+	Pos
+	Keyword token.Token
+	Value   Expr
+}
+
+func (t *Throw) Accept(v VisitorStmt) interface{} {
+	return v.VisitThrow(t)
+}
+
+type Try struct {
+	Pos
+	Body *Block
+	// Catch is nil when there is no catch clause,
+	// CatchParam is then meaningless
+	CatchParam token.Token
+	Catch      *Block
+	// Finally is nil when there is no finally clause
+	Finally *Block
+}
+
+func (t *Try) Accept(v VisitorStmt) interface{} {
+	return v.VisitTry(t)
+}
+
+type Import struct {
+	Pos
+	Keyword token.Token
+	// Name is the identifier the module is bound to,
+	// it defaults to the base name of the module's file
+	Name token.Token
+	Path string
+}
+
+func (i *Import) Accept(v VisitorStmt) interface{} {
+	return v.VisitImport(i)
+}
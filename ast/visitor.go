@@ -12,6 +12,13 @@ type VisitorExpr interface {
 	VisitGet(*Get) interface{}
 	VisitSet(*Set) interface{}
 	VisitThis(*This) interface{}
+	VisitSuper(*Super) interface{}
+	VisitList(*List) interface{}
+	VisitIndex(*Index) interface{}
+	VisitSlice(*Slice) interface{}
+	VisitSetIndex(*SetIndex) interface{}
+	VisitMap(*Map) interface{}
+	VisitLambda(*Lambda) interface{}
 }
 
 type VisitorStmt interface {
@@ -24,4 +31,9 @@ type VisitorStmt interface {
 	VisitFunction(f *Function) interface{}
 	VisitReturn(r *Return) interface{}
 	VisitClass(c *Class) interface{}
+	VisitBreak(b *Break) interface{}
+	VisitContinue(c *Continue) interface{}
+	VisitThrow(t *Throw) interface{}
+	VisitTry(t *Try) interface{}
+	VisitImport(i *Import) interface{}
 }
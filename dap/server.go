@@ -0,0 +1,440 @@
+// Package dap implements a Debug Adapter Protocol server running Lox
+// scripts with the tree-walk interpreter and its debugger, editors
+// set breakpoints, step and inspect the paused script through it.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/interpreter"
+	"github.com/taki-mekhalfa/golox/resolver"
+	"github.com/taki-mekhalfa/golox/value"
+)
+
+// Server debugs one script for a client, the requests are read on
+// the goroutine of Serve and the script runs on its own goroutine
+type Server struct {
+	in *textproto.Reader
+	// mu guards out and seq, messages are written from both goroutines
+	mu  sync.Mutex
+	out io.Writer
+	seq int
+
+	interpreter interpreter.Interpreter
+	debugger    interpreter.Debugger
+	program     string
+	src         string
+	stmts       []ast.Stmt
+	// entry is set until the first pause
+	entry bool
+	// done is closed when the script ended, nil until it runs
+	done chan struct{}
+
+	// state guards requests and terminating
+	state sync.Mutex
+	// requests are the requests handled by the paused script, nil while it runs
+	requests    chan *request
+	terminating bool
+}
+
+// Serve reads the requests of a client from in and writes the
+// responses and events to out until the client disconnects
+func Serve(in io.Reader, out io.Writer) error {
+	s := &Server{in: textproto.NewReader(bufio.NewReader(in)), out: out}
+	s.debugger.Paused = s.paused
+	s.debugger.Compile = s.compileExpr
+
+	for {
+		req, err := s.read()
+		if err == io.EOF {
+			s.terminate()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if req.Command == "disconnect" {
+			s.terminate()
+			return s.respond(req, nil)
+		}
+		if err := s.handle(req); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads a request, which is preceded by a
+// header giving the length of its content
+func (s *Server) read() (*request, error) {
+	header, err := s.in.ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("dap: invalid Content-Length: %q", header.Get("Content-Length"))
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(s.in.R, content); err != nil {
+		return nil, err
+	}
+	var req request
+	if err := json.Unmarshal(content, &req); err != nil {
+		return nil, fmt.Errorf("dap: invalid message: %v", err)
+	}
+	return &req, nil
+}
+
+// write writes a response or an event, setting its sequence number
+func (s *Server) write(msg interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	switch msg := msg.(type) {
+	case *response:
+		msg.Seq, msg.Type = s.seq, "response"
+	case *event:
+		msg.Seq, msg.Type = s.seq, "event"
+	}
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	return err
+}
+
+func (s *Server) respond(req *request, body interface{}) error {
+	return s.write(&response{RequestSeq: req.Seq, Command: req.Command, Success: true, Body: body})
+}
+
+func (s *Server) fail(req *request, msg string) error {
+	return s.write(&response{RequestSeq: req.Seq, Command: req.Command, Message: msg})
+}
+
+func (s *Server) event(name string, body interface{}) error {
+	return s.write(&event{Event: name, Body: body})
+}
+
+// handle answers a request read while the script runs or is
+// paused, the requests inspecting the script go to the paused script
+func (s *Server) handle(req *request) error {
+	switch req.Command {
+	case "initialize":
+		if err := s.respond(req, map[string]bool{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+			"supportsTerminateRequest":         true,
+		}); err != nil {
+			return err
+		}
+		return s.event("initialized", nil)
+	case "launch":
+		// the interpreter can't be set up again while the script runs
+		if s.done != nil {
+			return s.fail(req, "The script was already launched.")
+		}
+		var args LaunchArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return s.fail(req, err.Error())
+		}
+		if err := s.launch(args); err != nil {
+			return s.fail(req, err.Error())
+		}
+		return s.respond(req, nil)
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return s.fail(req, err.Error())
+		}
+		lines := make([]int, len(args.Breakpoints))
+		breakpoints := make([]Breakpoint, len(args.Breakpoints))
+		for i, b := range args.Breakpoints {
+			lines[i] = b.Line
+			breakpoints[i] = Breakpoint{Verified: true, Line: b.Line}
+		}
+		s.debugger.SetBreakpoints(args.Source.Path, lines)
+		return s.respond(req, map[string]interface{}{"breakpoints": breakpoints})
+	case "setExceptionBreakpoints":
+		// the script stops at the errors it doesn't catch
+		return s.respond(req, nil)
+	case "configurationDone":
+		if s.stmts == nil {
+			return s.fail(req, "No script was launched.")
+		}
+		if s.done != nil {
+			return s.fail(req, "The script is already running.")
+		}
+		if err := s.respond(req, nil); err != nil {
+			return err
+		}
+		s.run()
+		return nil
+	case "threads":
+		return s.respond(req, map[string]interface{}{"threads": []Thread{{ID: threadID, Name: "main"}}})
+	case "pause":
+		s.debugger.Interrupt()
+		return s.respond(req, nil)
+	case "terminate":
+		s.terminate()
+		return s.respond(req, nil)
+	}
+
+	s.state.Lock()
+	requests := s.requests
+	s.state.Unlock()
+	if requests == nil {
+		return s.fail(req, fmt.Sprintf("Can't handle %s while the script is not paused.", req.Command))
+	}
+	requests <- req
+	return nil
+}
+
+// launch compiles the program, it runs once the client is configured
+func (s *Server) launch(args LaunchArguments) error {
+	program, err := filepath.Abs(args.Program)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(program)
+	if err != nil {
+		return fmt.Errorf("Could not read the source file: %v", err)
+	}
+	s.program, s.src = program, string(b)
+
+	s.interpreter.Init()
+	s.interpreter.File = program
+	s.interpreter.Load = s.compile
+	s.interpreter.SearchPath = filepath.SplitList(os.Getenv("GOLOX_PATH"))
+	s.interpreter.Stdout = output{s, "stdout"}
+	// the client talks to the server over stdin
+	s.interpreter.Stdin = strings.NewReader("")
+	s.interpreter.Debugger = &s.debugger
+
+	s.stmts, err = s.compile(program, s.src)
+	if err != nil {
+		return fmt.Errorf("%s", s.render(err))
+	}
+	if args.StopOnEntry {
+		s.debugger.Step, s.entry = interpreter.StepIn, true
+	}
+	return nil
+}
+
+// run runs the script on its own goroutine
+func (s *Server) run() {
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		exitCode := 0
+		if err := s.interpreter.Interpret(s.stmts); err != nil {
+			exitCode = 65
+			s.state.Lock()
+			terminated := s.terminating
+			s.state.Unlock()
+			if !terminated {
+				s.event("output", map[string]string{"category": "stderr", "output": s.render(err) + "\n"})
+			}
+		}
+		s.event("exited", map[string]int{"exitCode": exitCode})
+		s.event("terminated", nil)
+	}()
+}
+
+// terminate stops the script and waits for it to end
+func (s *Server) terminate() {
+	if s.done == nil {
+		return
+	}
+	s.state.Lock()
+	s.terminating = true
+	requests := s.requests
+	s.state.Unlock()
+	if requests != nil {
+		requests <- &request{Command: "terminate"}
+	} else {
+		s.debugger.Interrupt()
+	}
+	<-s.done
+}
+
+// paused sends a stopped event and handles the requests
+// inspecting the script until one resumes it
+func (s *Server) paused(p *interpreter.Pause) interpreter.Step {
+	s.state.Lock()
+	if s.terminating {
+		s.state.Unlock()
+		return interpreter.Terminate
+	}
+	requests := make(chan *request)
+	s.requests = requests
+	s.state.Unlock()
+
+	reason := "step"
+	switch {
+	case s.entry:
+		reason, s.entry = "entry", false
+	case p.Breakpoint:
+		reason = "breakpoint"
+	case p.Interrupted:
+		reason = "pause"
+	}
+	s.event("stopped", map[string]interface{}{"reason": reason, "threadId": threadID, "allThreadsStopped": true})
+
+	// the references to the scopes and values inspected,
+	// they are valid until the script resumes
+	var references [][]interpreter.Variable
+	reference := func(vars []interpreter.Variable) int {
+		references = append(references, vars)
+		return len(references)
+	}
+	variables := func(vars []interpreter.Variable) []Variable {
+		converted := make([]Variable, len(vars))
+		for i, v := range vars {
+			converted[i] = Variable{Name: v.Name, Value: value.Stringify(v.Value)}
+			if members := interpreter.Members(v.Value); len(members) > 0 {
+				converted[i].VariablesReference = reference(members)
+			}
+		}
+		return converted
+	}
+
+	for req := range requests {
+		var step interpreter.Step
+		switch req.Command {
+		case "continue":
+			step = interpreter.Resume
+		case "next":
+			step = interpreter.StepOver
+		case "stepIn":
+			step = interpreter.StepIn
+		case "stepOut":
+			step = interpreter.StepOut
+		case "terminate":
+			s.state.Lock()
+			s.requests = nil
+			s.state.Unlock()
+			return interpreter.Terminate
+
+		case "stackTrace":
+			var frames []StackFrame
+			for i, f := range p.Stack() {
+				frames = append(frames, StackFrame{
+					ID:     i + 1,
+					Name:   f.Function,
+					Source: Source{Name: filepath.Base(f.File), Path: f.File},
+					Line:   f.Span.Line,
+					Column: f.Span.Column,
+				})
+			}
+			s.respond(req, map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)})
+			continue
+		case "scopes":
+			var args struct {
+				FrameID int `json:"frameId"`
+			}
+			json.Unmarshal(req.Arguments, &args)
+			scopes := []Scope{}
+			for _, scope := range p.Scopes(args.FrameID - 1) {
+				scopes = append(scopes, Scope{Name: scope.Name, VariablesReference: reference(scope.Variables)})
+			}
+			s.respond(req, map[string]interface{}{"scopes": scopes})
+			continue
+		case "variables":
+			var args struct {
+				VariablesReference int `json:"variablesReference"`
+			}
+			json.Unmarshal(req.Arguments, &args)
+			if args.VariablesReference < 1 || args.VariablesReference > len(references) {
+				s.fail(req, "Unknown variables reference.")
+				continue
+			}
+			s.respond(req, map[string]interface{}{"variables": variables(references[args.VariablesReference-1])})
+			continue
+		case "evaluate":
+			var args EvaluateArguments
+			json.Unmarshal(req.Arguments, &args)
+			frame := args.FrameID - 1
+			if frame < 0 {
+				frame = 0
+			}
+			v, err := p.Evaluate(frame, args.Expression)
+			if err != nil {
+				s.fail(req, s.render(err))
+				continue
+			}
+			result := Variable{Value: value.Stringify(v)}
+			if members := interpreter.Members(v); len(members) > 0 {
+				result.VariablesReference = reference(members)
+			}
+			s.respond(req, map[string]interface{}{"result": result.Value, "variablesReference": result.VariablesReference})
+			continue
+		default:
+			s.fail(req, fmt.Sprintf("Unsupported command %s.", req.Command))
+			continue
+		}
+
+		s.state.Lock()
+		s.requests = nil
+		s.state.Unlock()
+		s.respond(req, map[string]bool{"allThreadsContinued": true})
+		return step
+	}
+	return interpreter.Terminate
+}
+
+// compile scans, parses and resolves the code of file
+func (s *Server) compile(file, src string) ([]ast.Stmt, error) {
+	return resolver.Compile(file, src, &s.interpreter)
+}
+
+// compileExpr compiles an expression evaluated in a paused frame
+func (s *Server) compileExpr(code string, scopes [][]string) (ast.Expr, error) {
+	return resolver.CompileExpr(code, &s.interpreter, scopes)
+}
+
+// render renders the diagnostics of err, with the code of
+// the program for the ones about it
+func (s *Server) render(err error) string {
+	diags, ok := err.(diag.List)
+	if !ok {
+		return err.Error()
+	}
+	rendered := make([]string, len(diags))
+	for i, d := range diags {
+		if d.File == s.program {
+			rendered[i] = d.Render(s.src)
+		} else {
+			rendered[i] = d.Error()
+		}
+	}
+	return strings.Join(rendered, "\n")
+}
+
+// output sends what the script prints as output events
+type output struct {
+	s        *Server
+	category string
+}
+
+func (o output) Write(p []byte) (int, error) {
+	if err := o.s.event("output", map[string]string{"category": o.category, "output": string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/interpreter"
+	"github.com/taki-mekhalfa/golox/resolver"
+	"github.com/taki-mekhalfa/golox/value"
+)
+
+const debugHelp = `Commands:
+  break, b <line>     pause at a line of the script, or at <file>:<line>
+  clear <line>        remove a breakpoint, or all of them without a line
+  continue, c         run until the next breakpoint
+  step, s             run to the next statement, stepping into calls
+  next, n             run to the next statement, stepping over calls
+  out, o              run until the current function returns
+  stack, bt           print the calls being executed
+  frame, f <n>        inspect the frame n of the stack, 0 is the innermost
+  vars, v             print the variables of the frame
+  print, p <expr>     evaluate an expression in the frame
+  list, l             print the code around the statement of the frame
+  quit, q             stop the script
+An empty line repeats the last command.`
+
+// debugger is the state of the terminal debugger
+type debugger struct {
+	file string
+	// breakpoints are the lines to pause at by file
+	breakpoints map[string][]int
+	// sources caches the code of the files, read for listing them
+	sources map[string][]string
+	// frame is the index of the frame inspected in the stack
+	frame int
+	last  string
+}
+
+// debug runs the debug command, which runs a script
+// under the terminal debugger, it returns the exit code
+func debug(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: golox debug file.lox")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return EX_USAGE
+	}
+	file := flags.Arg(0)
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read the source file: %+v\n", err)
+		return 1
+	}
+
+	// the debugger only runs with the tree-walk interpreter
+	*useVM = false
+	setup()
+	d := &debugger{file: file, breakpoints: map[string][]int{}, sources: map[string][]string{}}
+	interpreter_.File = file
+	interpreter_.Stdin = stdin
+	interpreter_.Debugger = &interpreter.Debugger{Paused: d.paused, Compile: compileExpr, Step: interpreter.StepIn}
+	fmt.Println(`Paused before the first statement, type "help" for the commands.`)
+	if err := run(context.Background(), file, string(b)); err != nil {
+		return EX_DATAERR
+	}
+	fmt.Println("The script ended.")
+	return 0
+}
+
+// compileExpr compiles an expression typed in the debugger,
+// resolved in the scopes of the inspected frame
+func compileExpr(code string, scopes [][]string) (ast.Expr, error) {
+	return resolver.CompileExpr(code, &interpreter_, scopes)
+}
+
+// paused shows where the execution paused and runs the commands
+// typed until one resumes it
+func (d *debugger) paused(p *interpreter.Pause) interpreter.Step {
+	d.frame = 0
+	d.where(p)
+	for {
+		fmt.Print("(debug) ")
+		line, err := stdin.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			fmt.Println()
+			return interpreter.Terminate
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = d.last
+		}
+		d.last = line
+
+		name, arg := line, ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			name, arg = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		switch name {
+		case "":
+		case "help", "h":
+			fmt.Println(debugHelp)
+		case "break", "b":
+			d.setBreakpoint(arg, true)
+		case "clear":
+			d.setBreakpoint(arg, false)
+		case "continue", "c":
+			return interpreter.Resume
+		case "step", "s":
+			return interpreter.StepIn
+		case "next", "n":
+			return interpreter.StepOver
+		case "out", "o":
+			return interpreter.StepOut
+		case "quit", "q":
+			return interpreter.Terminate
+		case "stack", "bt":
+			for i, f := range p.Stack() {
+				marker := " "
+				if i == d.frame {
+					marker = "*"
+				}
+				fmt.Printf("%s %d %s at %s:%d\n", marker, i, f.Function, f.File, f.Span.Line)
+			}
+		case "frame", "f":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 || n >= len(p.Stack()) {
+				fmt.Fprintf(diagnostics, "Expected a frame between 0 and %d.\n", len(p.Stack())-1)
+				continue
+			}
+			d.frame = n
+			d.where(p)
+		case "vars", "v":
+			for _, scope := range p.Scopes(d.frame) {
+				fmt.Printf("%s:\n", scope.Name)
+				for _, v := range scope.Variables {
+					fmt.Printf("  %s = %s\n", v.Name, value.Stringify(v.Value))
+				}
+			}
+		case "print", "p":
+			v, err := p.Evaluate(d.frame, arg)
+			if err != nil {
+				report(err, "", arg)
+				continue
+			}
+			fmt.Println(value.Stringify(v))
+		case "list", "l":
+			f := p.Stack()[d.frame]
+			d.list(f.File, f.Span.Line, 5)
+		default:
+			fmt.Fprintf(diagnostics, "Unknown command %s, type help for the list of commands.\n", name)
+		}
+	}
+}
+
+// where prints the statement of the inspected frame
+func (d *debugger) where(p *interpreter.Pause) {
+	f := p.Stack()[d.frame]
+	reason := ""
+	if p.Breakpoint && d.frame == 0 {
+		reason = " (breakpoint)"
+	}
+	fmt.Printf("%s at %s:%d%s\n", f.Function, f.File, f.Span.Line, reason)
+	d.list(f.File, f.Span.Line, 0)
+}
+
+// list prints the lines of file around line, context lines before and after it
+func (d *debugger) list(file string, line, context int) {
+	lines, ok := d.sources[file]
+	if !ok {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return
+		}
+		lines = strings.Split(string(b), "\n")
+		d.sources[file] = lines
+	}
+	for n := line - context; n <= line+context; n++ {
+		if n < 1 || n > len(lines) {
+			continue
+		}
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Printf("%s %4d | %s\n", marker, n, strings.TrimRight(lines[n-1], "\r"))
+	}
+}
+
+// setBreakpoint adds or removes the breakpoint at [file:]line,
+// clearing without a line removes all the breakpoints
+func (d *debugger) setBreakpoint(arg string, set bool) {
+	if arg == "" && !set {
+		for file := range d.breakpoints {
+			interpreter_.Debugger.SetBreakpoints(file, nil)
+		}
+		d.breakpoints = map[string][]int{}
+		return
+	}
+	file, at := d.file, arg
+	if i := strings.LastIndex(arg, ":"); i >= 0 {
+		file, at = arg[:i], arg[i+1:]
+	}
+	line, err := strconv.Atoi(at)
+	if err != nil || line < 1 {
+		fmt.Fprintln(diagnostics, "Expected a line number, or <file>:<line>.")
+		return
+	}
+
+	var lines []int
+	for _, l := range d.breakpoints[file] {
+		if l != line {
+			lines = append(lines, l)
+		}
+	}
+	if set {
+		lines = append(lines, line)
+		sort.Ints(lines)
+	}
+	d.breakpoints[file] = lines
+	interpreter_.Debugger.SetBreakpoints(file, lines)
+	if set {
+		fmt.Printf("Breakpoint at %s:%d\n", file, line)
+	}
+}
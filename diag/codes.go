@@ -0,0 +1,73 @@
+package diag
+
+// Code identifies a kind of diagnostic, codes are
+// stable and never reused for another kind.
+type Code string
+
+// scanner
+const (
+	UnexpectedCharacter Code = "S001"
+	UnterminatedString  Code = "S002"
+	UnterminatedComment Code = "S003"
+	InvalidEscape       Code = "S004"
+)
+
+// parser
+const (
+	ExpectedToken           Code = "P001"
+	ExpectedExpression      Code = "P002"
+	InvalidAssignmentTarget Code = "P003"
+	InvalidModuleName       Code = "P004"
+)
+
+// resolver
+const (
+	InheritFromSelf        Code = "R001"
+	ReadInOwnInitializer   Code = "R002"
+	ReturnFromInitializer  Code = "R003"
+	ReturnOutsideFunction  Code = "R004"
+	BreakOutsideLoop       Code = "R005"
+	ContinueOutsideLoop    Code = "R006"
+	ThisOutsideClass       Code = "R007"
+	SuperOutsideClass      Code = "R008"
+	SuperWithoutSuperclass Code = "R009"
+	AlreadyDeclared        Code = "R010"
+	UnusedVariable         Code = "R011"
+)
+
+// bytecode compiler
+const (
+	TooManyArguments Code = "C001"
+	TooManyLocals    Code = "C002"
+	TooManyUpvalues  Code = "C003"
+	TooManyConstants Code = "C004"
+	JumpTooLarge     Code = "C005"
+)
+
+// runtime
+const (
+	UndefinedVariable Code = "E001"
+	UndefinedProperty Code = "E002"
+	TypeError         Code = "E003"
+	NotCallable       Code = "E004"
+	ArityMismatch     Code = "E005"
+	DivisionByZero    Code = "E006"
+	IndexOutOfRange   Code = "E007"
+	UndefinedKey      Code = "E008"
+	ImportError       Code = "E009"
+	// NativeError is raised by a function of the host returning an error
+	NativeError       Code = "E010"
+	UncaughtException Code = "E011"
+	StackOverflow     Code = "E012"
+	// Cancelled and BudgetExceeded stop the execution,
+	// scripts can't catch them
+	Cancelled      Code = "E013"
+	BudgetExceeded Code = "E014"
+	// ConversionError is raised converting a string that is not a number
+	ConversionError Code = "E015"
+)
+
+// Catchable reports whether scripts can catch the runtime errors with the code
+func Catchable(code Code) bool {
+	return code != Cancelled && code != BudgetExceeded
+}
@@ -0,0 +1,199 @@
+// Package diag defines the diagnostics reported while scanning,
+// parsing, resolving, compiling and running Lox programs.
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/taki-mekhalfa/golox/token"
+)
+
+// Phase is the step of the pipeline that reported a diagnostic
+type Phase int
+
+const (
+	Scan Phase = iota
+	Parse
+	Resolve
+	Compile
+	Runtime
+)
+
+var phases = [...]string{
+	Scan:    "scan",
+	Parse:   "parse",
+	Resolve: "resolve",
+	Compile: "compile",
+	Runtime: "runtime",
+}
+
+func (p Phase) String() string {
+	if p < 0 || int(p) >= len(phases) {
+		return fmt.Sprintf("Phase(%d)", int(p))
+	}
+	return phases[p]
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (p Phase) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Diagnostic is a problem found in a program
+type Diagnostic struct {
+	Phase    Phase    `json:"phase"`
+	Severity Severity `json:"severity"`
+	// File is empty for the prompt and sources without a path
+	File string `json:"file,omitempty"`
+	Line int    `json:"line"`
+	// Column is 1-based and counts characters, 0 when it is unknown
+	Column int `json:"column"`
+	// Offset and End are the byte offsets of the range of source
+	// the diagnostic is about, End is 0 when it is unknown
+	Offset  int    `json:"offset"`
+	End     int    `json:"end"`
+	Message string `json:"message"`
+	Code    Code   `json:"code"`
+	// Trace lists the calls that were active when a runtime
+	// error was raised, from the innermost to the script
+	Trace []Frame `json:"trace,omitempty"`
+}
+
+// Frame is a call active when a runtime error was raised
+type Frame struct {
+	// Function is the name of the called function, prefixed by the
+	// class of methods, or <script>, <fn> for lambdas and <module name>
+	Function string `json:"function"`
+	File     string `json:"file,omitempty"`
+	// Line is where the error was raised in the innermost
+	// frame and where the next call was made in the others
+	Line int `json:"line"`
+}
+
+func (f Frame) String() string {
+	if strings.HasPrefix(f.Function, "<") {
+		return fmt.Sprintf("%s line %d", f.Function, f.Line)
+	}
+	return fmt.Sprintf("%s() line %d", f.Function, f.Line)
+}
+
+// New returns an error diagnostic about the given range of source
+func New(phase Phase, span token.Span, code Code, message string) Diagnostic {
+	return Diagnostic{
+		Phase:   phase,
+		File:    span.File,
+		Line:    span.Line,
+		Column:  span.Column,
+		Offset:  span.Offset,
+		End:     span.End,
+		Message: message,
+		Code:    code,
+	}
+}
+
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("[line %d] %s: %s", d.Line, d.label(), d.Message)
+}
+
+func (d Diagnostic) label() string {
+	kind := "Runtime"
+	switch d.Phase {
+	case Scan, Parse:
+		kind = "Syntax"
+	case Resolve:
+		kind = "Resolve"
+	case Compile:
+		kind = "Compile"
+	}
+	if d.Severity == Warning {
+		return kind + " Warning"
+	}
+	return kind + " Error"
+}
+
+// List is a list of diagnostics in the order they were reported
+type List []Diagnostic
+
+func (l List) Error() string {
+	msgs := make([]string, len(l))
+	for i, d := range l {
+		msgs[i] = d.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Err returns the list as an error, or nil when it holds no error
+func (l List) Err() error {
+	for _, d := range l {
+		if d.Severity == Error {
+			return l
+		}
+	}
+	return nil
+}
+
+// codedError is an error carrying the code of the diagnostic it becomes
+type codedError struct {
+	code Code
+	msg  string
+	// causes are the diagnostics reported along with it
+	causes List
+}
+
+func (e *codedError) Error() string {
+	return e.msg
+}
+
+// Errorf formats an error tagged with a diagnostic code
+func Errorf(code Code, format string, args ...interface{}) error {
+	return &codedError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// Wrapf is like Errorf, the error is caused by the diagnostics
+// of causes, such as the syntax errors of an imported module
+func Wrapf(causes List, code Code, format string, args ...interface{}) error {
+	return &codedError{code: code, msg: fmt.Sprintf(format, args...), causes: causes}
+}
+
+// CausesOf returns the diagnostics err was wrapped with by Wrapf
+func CausesOf(err error) List {
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.causes
+	}
+	return nil
+}
+
+// CodeOf returns the code err was tagged with by Errorf, or
+// NativeError for errors returned by functions of the host.
+func CodeOf(err error) Code {
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	return NativeError
+}
@@ -0,0 +1,113 @@
+package diag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Render renders the diagnostic followed by the line of src it is about
+// with the offending range underlined, src is the source of d.File.
+// errors raised inside of a call are followed by their trace.
+//
+//	main.lox:3:11: Runtime Error: Operands must be both numbers.
+//	 3 |   return 1 + (2 < "a");
+//	   |              ^~~~~~~
+//	  at compare() line 3
+//	  at <script> line 5
+func (d Diagnostic) Render(src string) string {
+	var b strings.Builder
+	location := strconv.Itoa(d.Line)
+	if d.Column != 0 {
+		location += ":" + strconv.Itoa(d.Column)
+	}
+	if d.File != "" {
+		location = d.File + ":" + location
+	}
+	fmt.Fprintf(&b, "%s: %s: %s", location, d.label(), d.Message)
+	d.underline(&b, src)
+
+	// errors raised at the top level of the script have nothing to trace
+	if len(d.Trace) > 1 {
+		d.writeTrace(&b)
+	}
+	return b.String()
+}
+
+// traceEdge is the number of lines of a long trace
+// written at each end, the ones in between are elided
+const traceEdge = 10
+
+// writeTrace writes the frames of the trace, the runs of identical
+// frames left by a recursion are written once with their count
+func (d Diagnostic) writeTrace(b *strings.Builder) {
+	var lines []string
+	for i := 0; i < len(d.Trace); {
+		f := d.Trace[i]
+		line := "at " + f.String()
+		if f.File != d.File {
+			line += " in " + f.File
+		}
+		lines = append(lines, line)
+		n := 1
+		for i+n < len(d.Trace) && d.Trace[i+n] == f {
+			n++
+		}
+		if n > 1 {
+			lines = append(lines, fmt.Sprintf("... repeated %d more times", n-1))
+		}
+		i += n
+	}
+
+	if len(lines) > 2*traceEdge+1 {
+		elided := fmt.Sprintf("... %d more lines", len(lines)-2*traceEdge)
+		lines = append(append(lines[:traceEdge:traceEdge], elided), lines[len(lines)-traceEdge:]...)
+	}
+	for _, line := range lines {
+		b.WriteString("\n  " + line)
+	}
+}
+
+// underline writes the line of src the diagnostic is
+// about with a caret under the offending range
+func (d Diagnostic) underline(b *strings.Builder, src string) {
+	line, ok := sourceLine(src, d.Line)
+	if !ok || d.Column == 0 || d.Column-1 > utf8.RuneCountInString(line) {
+		return
+	}
+
+	gutter := strconv.Itoa(d.Line)
+	fmt.Fprintf(b, "\n %s | %s\n %s | ", gutter, line, strings.Repeat(" ", len(gutter)))
+	// the column counts characters, keep the tabs
+	// of the line so that the caret lines up
+	start := 0
+	for column := 1; column < d.Column; column++ {
+		c, size := utf8.DecodeRuneInString(line[start:])
+		if c == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+		start += size
+	}
+	// ranges spanning several lines are underlined up to the end of the first one
+	end := start + d.End - d.Offset
+	if end > len(line) {
+		end = len(line)
+	}
+	width := 1
+	if end > start {
+		width = utf8.RuneCountInString(line[start:end])
+	}
+	b.WriteString("^" + strings.Repeat("~", width-1))
+}
+
+// sourceLine returns the given 1-based line of src
+func sourceLine(src string, line int) (string, bool) {
+	lines := strings.Split(src, "\n")
+	if line < 1 || line > len(lines) {
+		return "", false
+	}
+	return strings.TrimSuffix(lines[line-1], "\r"), true
+}
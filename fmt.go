@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/taki-mekhalfa/golox/format"
+)
+
+// formatFiles runs the fmt command, which prints the formatted
+// files or with -w writes them back, it returns the exit code
+func formatFiles(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the formatted code to the files instead of printing it")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: golox fmt [-w] files...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return EX_USAGE
+	}
+
+	code := 0
+	for _, file := range flags.Args() {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not read the source file: %+v\n", err)
+			code = 1
+			continue
+		}
+		formatted, err := format.Source(file, string(b))
+		if err != nil {
+			report(err, file, string(b))
+			code = EX_DATAERR
+			continue
+		}
+		if !*write {
+			fmt.Print(formatted)
+			continue
+		}
+		if formatted == string(b) {
+			continue
+		}
+		if err := ioutil.WriteFile(file, []byte(formatted), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not write the source file: %+v\n", err)
+			code = 1
+		}
+	}
+	return code
+}
@@ -0,0 +1,558 @@
+// Package format formats Lox source code in a canonical style: statements
+// on their own lines indented by two spaces, single spaces around binary
+// operators and opening braces on the line of their statement. the comments
+// of the source are kept and formatting formatted code leaves it unchanged.
+package format
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/parser"
+	"github.com/taki-mekhalfa/golox/scanner"
+	"github.com/taki-mekhalfa/golox/token"
+)
+
+const indentation = "  "
+
+// Source formats the code of file, the
+// returned error is a diag.List of its syntax errors
+func Source(file, src string) (string, error) {
+	scanner := scanner.Scanner{File: file}
+	scanner.Init(src)
+	if err := scanner.Scan(); err != nil {
+		return "", err
+	}
+	parser := parser.Parser{}
+	parser.Init(scanner.Tokens())
+	stmts, err := parser.Parse()
+	if err != nil {
+		return "", err
+	}
+
+	f := &formatter{src: src, lines: []int{0}}
+	for i, c := range src {
+		if c == '\n' {
+			f.lines = append(f.lines, i+1)
+		}
+	}
+	for _, t := range scanner.Tokens() {
+		f.comments = append(f.comments, t.Comments...)
+	}
+	f.stmts(stmts, len(src))
+	return f.b.String(), nil
+}
+
+// formatter writes the statements in order and the comments of the source
+// in between. a comment is kept on the line of the code preceding it, or on
+// its own line before the code following it, the comments in an expression
+// stay before their token. blank lines between statements are kept,
+// collapsed to one.
+type formatter struct {
+	src string
+	// lines are the offsets of the lines of src
+	lines []int
+	// comments are the comments of src, in order
+	comments []token.Comment
+	// next is the index of the next comment to write
+	next int
+
+	b      strings.Builder
+	indent int
+	// last is the line of src of the last code or comment
+	// written, 0 at the start of a block
+	last int
+	// commented is the length of b after the last line comment written
+	// at the end of a line, the line can't go on after it
+	commented int
+}
+
+// lineOf returns the 1-based line of src holding offset
+func (f *formatter) lineOf(offset int) int {
+	return sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > offset })
+}
+
+// startOf and endOf return the first and last lines of src spanned by n
+func (f *formatter) startOf(n interface{ Position() token.Span }) int {
+	return f.lineOf(n.Position().Offset)
+}
+
+func (f *formatter) endOf(n interface{ Position() token.Span }) int {
+	return f.lineOf(n.Position().End - 1)
+}
+
+// open starts a line for code or a comment found at line of src,
+// after a blank line if there was one before it in src
+func (f *formatter) open(line int) {
+	if f.last != 0 && line > f.last+1 {
+		f.b.WriteString("\n")
+	}
+	f.b.WriteString(strings.Repeat(indentation, f.indent))
+	f.last = line
+}
+
+// comment writes the comments before the offset on their own lines
+func (f *formatter) comment(before int) {
+	for f.next < len(f.comments) && f.comments[f.next].Span.Offset < before {
+		c := f.comments[f.next]
+		f.next++
+		f.open(c.Span.Line)
+		f.b.WriteString(strings.TrimRight(c.Text, "\r") + "\n")
+		f.last = f.lineOf(c.Span.End - 1)
+	}
+}
+
+// trailing writes the comments found at the end of line
+// of src, before the code at the offset
+func (f *formatter) trailing(line, before int) {
+	for f.next < len(f.comments) && f.comments[f.next].Span.Line == line && f.comments[f.next].Span.Offset < before {
+		c := f.comments[f.next]
+		f.next++
+		f.append(c.Text)
+		f.last = f.lineOf(c.Span.End - 1)
+	}
+}
+
+// inner writes the comments before the offset at the end of the line
+func (f *formatter) inner(before int) {
+	for f.next < len(f.comments) && f.comments[f.next].Span.Offset < before {
+		f.append(f.comments[f.next].Text)
+		f.next++
+	}
+}
+
+// append writes a comment at the end of the line, on
+// the next one if the line ends with a line comment
+func (f *formatter) append(text string) {
+	text = strings.TrimRight(text, "\r")
+	if f.commented == f.b.Len() && f.commented != 0 {
+		f.b.WriteString("\n" + strings.Repeat(indentation, f.indent) + text)
+	} else {
+		f.b.WriteString(" " + text)
+	}
+	if strings.HasPrefix(text, "//") {
+		f.commented = f.b.Len()
+	}
+}
+
+// leading returns the comments before the offset to write in an expression
+// before the token at the offset, the expression goes on on the next line
+// after a line comment
+func (f *formatter) leading(before int) string {
+	var b strings.Builder
+	for f.next < len(f.comments) && f.comments[f.next].Span.Offset < before {
+		text := strings.TrimRight(f.comments[f.next].Text, "\r")
+		f.next++
+		if strings.HasPrefix(text, "//") {
+			b.WriteString(text + "\n" + strings.Repeat(indentation, f.indent+1))
+		} else {
+			b.WriteString(text + " ")
+		}
+	}
+	return b.String()
+}
+
+// skip returns the offset of the code at or after
+// the offset, past the spaces and the comments
+func (f *formatter) skip(offset int) int {
+	i := f.next
+	for offset < len(f.src) {
+		switch f.src[offset] {
+		case ' ', '\t', '\r', '\n':
+			offset++
+			continue
+		}
+		for i < len(f.comments) && f.comments[i].Span.Offset < offset {
+			i++
+		}
+		if i == len(f.comments) || f.comments[i].Span.Offset != offset {
+			break
+		}
+		offset = f.comments[i].Span.End
+	}
+	return offset
+}
+
+// stmts writes a list of statements ending before the offset
+func (f *formatter) stmts(stmts []ast.Stmt, end int) {
+	for i, s := range stmts {
+		next := end
+		if i+1 < len(stmts) {
+			next = stmts[i+1].Position().Offset
+		}
+		f.stmt(s, next)
+	}
+	f.comment(end)
+}
+
+// stmt writes a statement on its own lines, the
+// code following it in src starts at the offset
+func (f *formatter) stmt(s ast.Stmt, next int) {
+	f.comment(s.Position().Offset)
+	start, end := f.startOf(s), f.endOf(s)
+
+	switch s := s.(type) {
+	case *ast.Block:
+		f.open(start)
+		if loop, ok := forLoop(s); ok {
+			if f.for_(loop, next) {
+				return
+			}
+		} else {
+			f.block(s.Content, start, s.Position().End-1)
+		}
+	case *ast.While:
+		f.open(start)
+		var ended bool
+		if s.For != nil {
+			ended = f.for_(s, next)
+		} else {
+			f.b.WriteString("while (" + f.expr(s.Condition) + ")")
+			ended = f.body(s.Body, start, next)
+		}
+		if ended {
+			return
+		}
+	case *ast.If:
+		f.open(start)
+		if f.if_(s, start, next) {
+			return
+		}
+	case *ast.Function:
+		f.open(start)
+		f.b.WriteString("fun ")
+		f.function(s, s.Name.Lexeme)
+	case *ast.Class:
+		f.open(start)
+		f.class(s)
+	case *ast.Try:
+		f.open(start)
+		f.b.WriteString("try ")
+		f.block(s.Body.Content, start, s.Body.Position().End-1)
+		if s.Catch != nil {
+			f.b.WriteString(" catch (" + s.CatchParam.Lexeme + ") ")
+			f.block(s.Catch.Content, f.startOf(s.Catch), s.Catch.Position().End-1)
+		}
+		if s.Finally != nil {
+			f.b.WriteString(" finally ")
+			f.block(s.Finally.Content, f.startOf(s.Finally), s.Finally.Position().End-1)
+		}
+	default:
+		f.open(start)
+		f.b.WriteString(f.simple(s))
+		// the comments inside of a simple statement are moved
+		// to the end of its line as it is joined into one
+		f.inner(s.Position().End)
+	}
+	f.trailing(end, next)
+	f.b.WriteString("\n")
+	if end > f.last {
+		f.last = end
+	}
+}
+
+// simple returns the text of a statement without a body
+func (f *formatter) simple(s ast.Stmt) string {
+	switch s := s.(type) {
+	case *ast.Print:
+		return "print " + f.expr(s.Expr) + ";"
+	case *ast.ExprStmt:
+		return f.expr(s.Expr) + ";"
+	case *ast.VarStmt:
+		if s.Initializer == nil {
+			return "var " + s.Name + ";"
+		}
+		return "var " + s.Name + " = " + f.expr(s.Initializer) + ";"
+	case *ast.Return:
+		if s.Value == nil {
+			return "return;"
+		}
+		return "return " + f.expr(s.Value) + ";"
+	case *ast.Break:
+		return "break;"
+	case *ast.Continue:
+		return "continue;"
+	case *ast.Throw:
+		return "throw " + f.expr(s.Value) + ";"
+	case *ast.Import:
+		// the name of a module defaults to the name of its file
+		if f.src[s.Name.Offset] == '"' {
+			return "import " + quote(s.Path) + ";"
+		}
+		return "import " + s.Name.Lexeme + " from " + quote(s.Path) + ";"
+	}
+	panic("format: unexpected statement")
+}
+
+// block writes braces around the statements, the opening
+// brace ends the line header of src and the closing one
+// is at the offset
+func (f *formatter) block(stmts []ast.Stmt, header, close int) {
+	f.b.WriteString("{")
+	first := close
+	if len(stmts) > 0 {
+		first = stmts[0].Position().Offset
+	}
+	if len(stmts) == 0 && (f.next == len(f.comments) || f.comments[f.next].Span.Offset >= close) {
+		f.b.WriteString("}")
+		return
+	}
+
+	f.trailing(header, first)
+	f.b.WriteString("\n")
+	f.indent++
+	f.last = 0
+	f.stmts(stmts, close)
+	f.indent--
+	f.b.WriteString(strings.Repeat(indentation, f.indent) + "}")
+	f.last = f.lineOf(close)
+}
+
+// body writes the body of an if or a loop after its header found
+// at the given line of src, a body that isn't a block is written
+// on the next line. ended reports whether the line was ended.
+func (f *formatter) body(s ast.Stmt, header, next int) (ended bool) {
+	if b, ok := s.(*ast.Block); ok {
+		if _, ok := forLoop(b); !ok {
+			f.b.WriteString(" ")
+			f.block(b.Content, header, b.Position().End-1)
+			return false
+		}
+	}
+
+	f.trailing(header, s.Position().Offset)
+	f.b.WriteString("\n")
+	f.indent++
+	f.last = 0
+	f.stmt(s, next)
+	f.indent--
+	return true
+}
+
+func (f *formatter) if_(s *ast.If, header, next int) (ended bool) {
+	f.b.WriteString("if (" + f.expr(s.Condition) + ")")
+	if s.Else == nil {
+		return f.body(s.Then, header, next)
+	}
+
+	// the comments before else are kept before it, else starts a
+	// line after a line comment or a comment on a line of its own
+	else_ := f.skip(s.Then.Position().End)
+	ended = f.body(s.Then, header, else_)
+	if !ended {
+		close := f.endOf(s.Then)
+		for i := f.next; i < len(f.comments) && f.comments[i].Span.Offset < else_; i++ {
+			if c := f.comments[i]; c.Span.Line != close || strings.HasPrefix(c.Text, "//") {
+				f.trailing(close, else_)
+				f.b.WriteString("\n")
+				ended = true
+				break
+			}
+		}
+		if !ended {
+			f.inner(else_)
+		}
+	}
+	if ended {
+		f.comment(else_)
+		// else follows the body without a blank line
+		f.open(f.last + 1)
+		f.b.WriteString("else")
+	} else {
+		f.b.WriteString(" else")
+	}
+	if elseIf, ok := s.Else.(*ast.If); ok {
+		f.b.WriteString(" ")
+		return f.if_(elseIf, f.startOf(elseIf), next)
+	}
+	return f.body(s.Else, f.startOf(s.Else), next)
+}
+
+// forLoop returns the loop of a for statement with
+// an initializer, which is desugared into a block
+func forLoop(b *ast.Block) (*ast.While, bool) {
+	if len(b.Content) != 2 {
+		return nil, false
+	}
+	loop, ok := b.Content[1].(*ast.While)
+	if !ok || loop.For == nil || loop.For.Initializer != b.Content[0] {
+		return nil, false
+	}
+	return loop, true
+}
+
+func (f *formatter) for_(loop *ast.While, next int) (ended bool) {
+	f.b.WriteString("for (")
+	if loop.For.Initializer != nil {
+		f.b.WriteString(f.simple(loop.For.Initializer))
+	} else {
+		f.b.WriteString(";")
+	}
+	if loop.For.Condition != nil {
+		f.b.WriteString(" " + f.expr(loop.For.Condition))
+	}
+	f.b.WriteString(";")
+	if loop.Increment != nil {
+		f.b.WriteString(" " + f.expr(loop.Increment))
+	}
+	f.b.WriteString(")")
+	return f.body(loop.Body, f.startOf(loop), next)
+}
+
+// function writes the parameters and the body of a function after its name
+func (f *formatter) function(fn *ast.Function, name string) {
+	params := make([]string, len(fn.Params))
+	for i, param := range fn.Params {
+		params[i] = f.leading(param.Offset) + param.Lexeme
+	}
+	f.b.WriteString(name + "(" + strings.Join(params, ", ") + ") ")
+	f.block(fn.Body, f.lineOf(fn.Name.Offset), fn.Position().End-1)
+}
+
+func (f *formatter) class(c *ast.Class) {
+	f.b.WriteString("class " + c.Name.Lexeme)
+	if c.Superclass != nil {
+		f.b.WriteString(" < " + c.Superclass.Token.Lexeme)
+	}
+	close := c.Position().End - 1
+	if len(c.Methods) == 0 && (f.next == len(f.comments) || f.comments[f.next].Span.Offset >= close) {
+		f.b.WriteString(" {}")
+		return
+	}
+
+	first := close
+	if len(c.Methods) > 0 {
+		first = c.Methods[0].Position().Offset
+	}
+	f.b.WriteString(" {")
+	f.trailing(f.lineOf(c.Name.Offset), first)
+	f.b.WriteString("\n")
+	f.indent++
+	f.last = 0
+	for i, method := range c.Methods {
+		next := close
+		if i+1 < len(c.Methods) {
+			next = c.Methods[i+1].Position().Offset
+		}
+		f.comment(method.Position().Offset)
+		f.open(f.startOf(method))
+		f.function(method, method.Name.Lexeme)
+		f.trailing(f.endOf(method), next)
+		f.b.WriteString("\n")
+	}
+	f.comment(close)
+	f.indent--
+	f.b.WriteString(strings.Repeat(indentation, f.indent) + "}")
+	f.last = f.lineOf(close)
+}
+
+// expr returns the text of an expression, with the
+// comments found before it
+func (f *formatter) expr(e ast.Expr) string {
+	comments := f.leading(e.Position().Offset)
+	return comments + f.text(e)
+}
+
+func (f *formatter) text(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.Literal:
+		// literals are written as they were, with their escape sequences
+		span := e.Position()
+		return f.src[span.Offset:span.End]
+	case *ast.Var:
+		return e.Token.Lexeme
+	case *ast.Grouping:
+		return "(" + f.expr(e.Expr) + ")"
+	case *ast.Unary:
+		return e.Operator.Lexeme + f.expr(e.Expr)
+	case *ast.Binary:
+		return f.expr(e.Left) + " " + f.leading(e.Operator.Offset) + e.Operator.Lexeme + " " + f.expr(e.Right)
+	case *ast.Logical:
+		return f.expr(e.Left) + " " + f.leading(e.Operator.Offset) + e.Operator.Lexeme + " " + f.expr(e.Right)
+	case *ast.Assign:
+		return e.Identifier.Lexeme + " = " + f.expr(e.Value)
+	case *ast.Call:
+		return f.expr(e.Callee) + "(" + f.exprs(e.Args) + ")"
+	case *ast.Get:
+		return f.expr(e.Object) + "." + e.Property.Lexeme
+	case *ast.Set:
+		return f.expr(e.Object) + "." + e.Property.Lexeme + " = " + f.expr(e.Value)
+	case *ast.This:
+		return "this"
+	case *ast.Super:
+		return "super." + e.Method.Lexeme
+	case *ast.List:
+		return "[" + f.exprs(e.Elements) + "]"
+	case *ast.Map:
+		entries := make([]string, len(e.Keys))
+		for i := range e.Keys {
+			entries[i] = f.expr(e.Keys[i]) + ": " + f.expr(e.Values[i])
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case *ast.Index:
+		return f.expr(e.Object) + "[" + f.expr(e.Index) + "]"
+	case *ast.Slice:
+		object := f.expr(e.Object)
+		var start, end string
+		if e.Start != nil {
+			start = f.expr(e.Start)
+		}
+		if e.End != nil {
+			end = f.expr(e.End)
+		}
+		return object + "[" + start + ":" + end + "]"
+	case *ast.SetIndex:
+		return f.expr(e.Object) + "[" + f.expr(e.Index) + "] = " + f.expr(e.Value)
+	case *ast.Lambda:
+		// the comments of the lambda are written in its body,
+		// they are taken from the ones left to the statement
+		span := e.Position()
+		lo := f.next
+		for lo < len(f.comments) && f.comments[lo].Span.Offset < span.Offset {
+			lo++
+		}
+		hi := lo
+		for hi < len(f.comments) && f.comments[hi].Span.Offset < span.End {
+			hi++
+		}
+		lambda := &formatter{src: f.src, lines: f.lines, comments: f.comments[lo:hi], indent: f.indent}
+		lambda.function(e.Function, "fun ")
+		f.comments = append(f.comments[:lo:lo], f.comments[hi:]...)
+		return lambda.b.String()
+	}
+	panic("format: unexpected expression")
+}
+
+func (f *formatter) exprs(exprs []ast.Expr) string {
+	texts := make([]string, len(exprs))
+	for i, e := range exprs {
+		texts[i] = f.expr(e)
+	}
+	return strings.Join(texts, ", ")
+}
+
+// quote returns s as a string literal
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, c := range s {
+		switch c {
+		case '"', '\\':
+			b.WriteRune('\\')
+			b.WriteRune(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		case 0:
+			b.WriteString(`\0`)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -1,96 +1,190 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
+	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/dap"
+	"github.com/taki-mekhalfa/golox/diag"
 	"github.com/taki-mekhalfa/golox/interpreter"
-	"github.com/taki-mekhalfa/golox/parser"
+	"github.com/taki-mekhalfa/golox/lsp"
 	"github.com/taki-mekhalfa/golox/resolver"
-	"github.com/taki-mekhalfa/golox/scanner"
+	"github.com/taki-mekhalfa/golox/vm"
 )
 
 const EX_USAGE = 64
 const EX_DATAERR = 65
 
-var syntaxErrFunc = func(line int, errMessage string) {
-	fmt.Printf("[line %d] Syntax Error: %s\n", line, errMessage)
-}
+var interpreter_ interpreter.Interpreter
+var vm_ vm.VM
 
-var runtimeErrFunc = func(line int, errMessage string) {
-	fmt.Printf("[line %d] Runtime Error: %s\n", line, errMessage)
-}
+// diagnostics is where the diagnostics are printed, apart from the output of scripts
+var diagnostics io.Writer = os.Stderr
 
-var interpreter_ = interpreter.Interpreter{Error: runtimeErrFunc}
+var useVM = flag.Bool("vm", false, "run scripts with the bytecode virtual machine instead of the tree-walk interpreter")
+var profile = flag.String("profile", "", "profile the Lox functions of the script and write a pprof profile to the file, or print a table when it is '-'")
+var searchPath = flag.String("path", "", "directories where imported modules are looked up, separated by '"+string(os.PathListSeparator)+"'")
 
-func run(code string) error {
-	scanner := scanner.Scanner{Error: syntaxErrFunc}
-	scanner.Init(code)
-	scanner.Scan()
-	if scanner.ErrorCount != 0 {
-		return fmt.Errorf("encountred %d scanner errors", scanner.ErrorCount)
+// compile scans, parses and resolves the code of file
+// into statements ready to be interpreted
+func compile(file, code string) ([]ast.Stmt, error) {
+	if *useVM {
+		return resolver.Compile(file, code, nil)
 	}
+	return resolver.Compile(file, code, &interpreter_)
+}
 
-	parser := parser.Parser{Error: syntaxErrFunc}
-	parser.Init(scanner.Tokens())
+// resolve resolves the statements parsed from an entry of the prompt
+func resolve(stmts []ast.Stmt) error {
+	resolver := resolver.Resolver{}
+	if !*useVM {
+		resolver.Interp = &interpreter_
+	}
+	return resolver.Resolve(stmts)
+}
 
-	stmts := parser.Parse()
-	if parser.ErrorCount != 0 {
-		return fmt.Errorf("encountred %d parser errors", parser.ErrorCount)
+// run compiles and runs the code of file until ctx is
+// done and prints the diagnostics it reported
+func run(ctx context.Context, file, code string) error {
+	stmts, err := compile(file, code)
+	if err == nil {
+		err = execute(ctx, stmts)
+	}
+	if err != nil {
+		report(err, file, code)
 	}
+	return err
+}
 
-	resolver := &resolver.Resolver{
-		Error:  runtimeErrFunc,
-		Interp: &interpreter_,
+// execute runs stmts with the selected backend until ctx is done
+func execute(ctx context.Context, stmts []ast.Stmt) error {
+	if *useVM {
+		return vm_.InterpretContext(ctx, stmts)
 	}
+	return interpreter_.InterpretContext(ctx, stmts)
+}
 
-	resolver.Resolve(stmts)
-	if resolver.ErrorCount != 0 {
-		return fmt.Errorf("encountred %d resolver errors", parser.ErrorCount)
+// report renders the diagnostics of err, the sources of
+// the imported modules they are about are read back
+func report(err error, file, code string) {
+	diags, ok := err.(diag.List)
+	if !ok {
+		fmt.Fprintln(diagnostics, err)
+		return
 	}
-	interpreter_.Interpret(stmts)
-	if interpreter_.ErrorCount != 0 {
-		return fmt.Errorf("encountred %d interpreter errors", parser.ErrorCount)
+	for _, d := range diags {
+		src := code
+		if d.File != file {
+			b, _ := ioutil.ReadFile(d.File)
+			src = string(b)
+		}
+		fmt.Fprintln(diagnostics, d.Render(src))
 	}
+}
 
-	return nil
+// setup initializes the interpreter and the virtual machine
+func setup() {
+	interpreter_ = interpreter.Interpreter{}
+	interpreter_.Init()
+	interpreter_.Load = compile
+	// the -path flag takes precedence over the GOLOX_PATH environment variable
+	interpreter_.SearchPath = append(filepath.SplitList(*searchPath), filepath.SplitList(os.Getenv("GOLOX_PATH"))...)
+
+	vm_ = vm.VM{}
+	vm_.Init()
+	vm_.Load = compile
+	vm_.SearchPath = interpreter_.SearchPath
 }
 
-func runPrompt() {
-	scanner := bufio.NewScanner(os.Stdin)
-	for {
-		fmt.Print(">> ")
-		if !scanner.Scan() {
-			if err := scanner.Err(); err != nil {
-				panic(err)
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			os.Exit(formatFiles(os.Args[2:]))
+		case "debug":
+			os.Exit(debug(os.Args[2:]))
+		case "dap":
+			// the client talks to the server over stdin and stdout
+			if err := dap.Serve(os.Stdin, os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "lsp":
+			// the client talks to the server over stdin and stdout
+			if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
 			}
-			break
+			os.Exit(0)
 		}
-		_ = run(scanner.Text())
-		interpreter_.ResetErrors()
 	}
-}
 
-func main() {
-	if len(os.Args) > 2 {
-		fmt.Println("Usage: golox [script]")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: golox [-vm] [-path dirs] [-profile file] [script]")
+		fmt.Fprintln(os.Stderr, "       golox fmt [-w] files...")
+		fmt.Fprintln(os.Stderr, "       golox debug file.lox")
+		fmt.Fprintln(os.Stderr, "       golox dap")
+		fmt.Fprintln(os.Stderr, "       golox lsp")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
 		os.Exit(EX_USAGE)
 	}
 
-	interpreter_.Init()
+	if *profile != "" && (*useVM || flag.NArg() == 0) {
+		fmt.Fprintln(os.Stderr, "The profiler runs scripts with the tree-walk interpreter.")
+		os.Exit(EX_USAGE)
+	}
 
-	if len(os.Args) == 2 {
-		b, err := ioutil.ReadFile(os.Args[1])
+	setup()
+	if flag.NArg() == 1 {
+		interpreter_.File = flag.Arg(0)
+		vm_.File = flag.Arg(0)
+		b, err := ioutil.ReadFile(flag.Arg(0))
 		if err != nil {
-			fmt.Printf("Could not read the source file: %+v", err)
+			fmt.Fprintf(os.Stderr, "Could not read the source file: %+v\n", err)
 			os.Exit(1)
 		}
-		if err := run(string(b)); err != nil {
+		if *profile != "" {
+			interpreter_.Profiler = &interpreter.Profiler{}
+		}
+		err = run(context.Background(), flag.Arg(0), string(b))
+		if *profile != "" {
+			if err := writeProfile(interpreter_.Profiler, *profile); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not write the profile: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if err != nil {
 			os.Exit(EX_DATAERR)
 		}
 	} else {
 		runPrompt()
 	}
 }
+
+// writeProfile prints the table of the profiled functions
+// when file is '-' and writes a pprof profile to it otherwise
+func writeProfile(p *interpreter.Profiler, file string) error {
+	if file == "-" {
+		return p.WriteTable(diagnostics)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := p.WritePprof(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,47 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/token"
+)
+
+// begin starts an execution from the host bounded by ctx, the
+// budgets are shared with the execution it is nested in, if any.
+// the returned function ends it.
+func (i *Interpreter) begin(ctx context.Context) (end func()) {
+	previous := i.ctx
+	if previous == nil {
+		i.steps, i.allocations = 0, 0
+	}
+	i.ctx = ctx
+	return func() { i.ctx = previous }
+}
+
+// tick is called at loop back-edges and calls to stop the
+// execution when it is cancelled or out of steps
+func (i *Interpreter) tick(at token.Span) {
+	i.steps++
+	if i.MaxSteps != 0 && i.steps > i.MaxSteps {
+		panic(runtimeError{span: at, code: diag.BudgetExceeded, msg: "Execution budget exceeded: too many steps."})
+	}
+	select {
+	case <-i.ctx.Done():
+		panic(runtimeError{span: at, code: diag.Cancelled, msg: fmt.Sprintf("Execution cancelled: %v.", i.ctx.Err())})
+	default:
+	}
+}
+
+// alloc is called when a list, map, string, instance or
+// function is created to stop the execution when it is out of allocations
+func (i *Interpreter) alloc(at token.Span) {
+	i.allocations++
+	if i.Profiler != nil {
+		i.Profiler.alloc()
+	}
+	if i.MaxAllocations != 0 && i.allocations > i.MaxAllocations {
+		panic(runtimeError{span: at, code: diag.BudgetExceeded, msg: "Execution budget exceeded: too many allocations."})
+	}
+}
@@ -1,41 +1,103 @@
 package interpreter
 
 import (
-	"time"
-
 	"github.com/taki-mekhalfa/golox/ast"
-)
-
-var (
-	// clockFn is a built-in function that returns the current time in unix seconds
-	clockFn = &clock{}
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/token"
+	"github.com/taki-mekhalfa/golox/value"
 )
 
 type callable interface {
-	call(*Interpreter, []interface{}) interface{}
+	// call takes the span of the call site
+	// to report errors raised while calling
+	call(interpreter *Interpreter, at token.Span, args []interface{}) interface{}
 	// returns the function's arity
 	arity() int
 }
 
-type clock struct{}
-
-func (c *clock) arity() int { return 0 }
-
-func (c *clock) call(interpreter *Interpreter, args []interface{}) interface{} {
-	return float64(time.Now().Unix())
-}
-
 type return_ struct {
 	value interface{}
 }
 
+// break_ and continue_ are panicked by the corresponding
+// statements and recovered by the innermost loop
+type break_ struct{}
+
+type continue_ struct{}
+
 type function struct {
 	closure     *environment
 	declaration *ast.Function
+	// class is the name of the class declaring a method
+	class string
+	// receiver and method are set on the methods bound to an instance
+	receiver *instance
+	method   *function
+}
+
+// frame is a call being executed
+type frame struct {
+	// name is the name of the called function in traces
+	name string
+	// at is the span of the call site
+	at token.Span
 }
 
 func (f *function) arity() int { return len(f.declaration.Params) }
-func (f *function) call(interpreter *Interpreter, args []interface{}) (ret interface{}) {
+
+// String implements fmt.Stringer
+func (f *function) String() string {
+	// lambdas are named after their 'fun' keyword
+	if f.declaration.Name.Type == token.FUN {
+		return "<fn>"
+	}
+	return "<fn " + f.declaration.Name.Lexeme + ">"
+}
+
+// enter pushes the frame of a call, a stack overflow
+// error is raised when there are too many nested calls
+func (i *Interpreter) enter(f frame) {
+	i.tick(f.at)
+	max := i.MaxDepth
+	if max == 0 {
+		max = value.DefaultMaxDepth
+	}
+	if len(i.frames) >= max {
+		panic(runtimeError{span: f.at, code: diag.StackOverflow, msg: "Stack overflow."})
+	}
+	i.frames = append(i.frames, f)
+}
+
+// name returns the name of the function in traces
+func (f *function) name() string {
+	if f.declaration.Name.Type == token.FUN {
+		return "<fn>"
+	}
+	if f.class != "" {
+		return f.class + "." + f.declaration.Name.Lexeme
+	}
+	return f.declaration.Name.Lexeme
+}
+
+// bind returns a copy of the method closing on an environment
+// where "this" refers to the given instance.
+func (f *function) bind(ins *instance) *function {
+	closure := newEnvironment(f.closure)
+	closure.define("this", ins)
+	return &function{declaration: f.declaration, closure: closure, class: f.class, receiver: ins, method: f}
+}
+
+// Equal implements value.Equaler, a method bound twice
+// to the same instance is equal to itself
+func (f *function) Equal(v interface{}) bool {
+	g, ok := v.(*function)
+	if !ok || f.receiver == nil || g.receiver == nil {
+		return v == f
+	}
+	return f.receiver == g.receiver && f.method == g.method
+}
+
+func (f *function) call(interpreter *Interpreter, at token.Span, args []interface{}) (ret interface{}) {
 	// save the current interpreter environment
 	previous := interpreter.env
 
@@ -50,10 +112,12 @@ func (f *function) call(interpreter *Interpreter, args []interface{}) (ret inter
 			return
 		}
 		if ret_, ok := err.(return_); ok {
+			interpreter.frames = interpreter.frames[:len(interpreter.frames)-1]
 			ret = ret_.value
 			return
 		}
-		// if the error is not nil or a return value, panic again to not silently hide the error
+		// if the error is not nil or a return value, panic again to not silently hide the error.
+		// the frame is left on the stack to be part of the trace of the error.
 		panic(err)
 	}()
 
@@ -70,9 +134,14 @@ func (f *function) call(interpreter *Interpreter, args []interface{}) (ret inter
 	// we don't use a block as a function body because a block will create
 	// a new scope which is not what we want.
 	// we want the arguments to be in the same scope as the function body.
+	interpreter.enter(frame{name: f.name(), at: at})
+	if interpreter.Profiler != nil {
+		defer interpreter.Profiler.enter(f)()
+	}
 	for _, stmt := range f.declaration.Body {
 		interpreter.evaluateStmt(stmt)
 	}
+	interpreter.frames = interpreter.frames[:len(interpreter.frames)-1]
 
 	return
 }
@@ -3,6 +3,7 @@ package interpreter
 import (
 	"fmt"
 
+	"github.com/taki-mekhalfa/golox/diag"
 	"github.com/taki-mekhalfa/golox/token"
 )
 
@@ -11,12 +12,24 @@ const (
 )
 
 type class struct {
-	name    string
-	methods map[string]*function
+	name       string
+	superclass *class
+	methods    map[string]*function
 }
 
-func newClass(name string) *class {
-	return &class{name: name, methods: make(map[string]*function)}
+func newClass(name string, superclass *class) *class {
+	return &class{name: name, superclass: superclass, methods: make(map[string]*function)}
+}
+
+// findMethod looks up a method in the class and then
+// walks up the inheritance chain until it finds it.
+func (c *class) findMethod(name string) (*function, bool) {
+	for klass := c; klass != nil; klass = klass.superclass {
+		if method, ok := klass.methods[name]; ok {
+			return method, true
+		}
+	}
+	return nil, false
 }
 
 // String implements fmt.Stringer
@@ -25,21 +38,20 @@ func (c *class) String() string {
 }
 
 func (c *class) arity() int {
-	if initializer, ok := c.methods[init_]; ok {
+	if initializer, ok := c.findMethod(init_); ok {
 		return initializer.arity()
 	}
 	return 0
 }
 
-func (c *class) call(interpreter *Interpreter, args []interface{}) (ret interface{}) {
+func (c *class) call(interpreter *Interpreter, at token.Span, args []interface{}) (ret interface{}) {
+	interpreter.alloc(at)
 	instance := newInstance(c)
 
 	// check if the user did provide an initializer,
 	// if so, call it before returning the instance.
-	if initializer, ok := c.methods[init_]; ok {
-		method := &function{declaration: initializer.declaration, closure: newEnvironment(initializer.closure)}
-		method.closure.define("this", instance)
-		method.call(interpreter, args)
+	if initializer, ok := c.findMethod(init_); ok {
+		initializer.bind(instance).call(interpreter, at, args)
 	}
 
 	// we always return the instance, even if the user had a 'return;'
@@ -69,14 +81,13 @@ func (ins *instance) get(t token.Token) interface{} {
 		return property
 	}
 	// don't allow code to access the `init` function
-	if method, ok := ins.klass.methods[t.Lexeme]; t.Lexeme != init_ && ok {
-		method := &function{declaration: method.declaration, closure: newEnvironment(method.closure)}
-		method.closure.define("this", ins)
-		return method
+	if method, ok := ins.klass.findMethod(t.Lexeme); t.Lexeme != init_ && ok {
+		return method.bind(ins)
 	}
 	panic(runtimeError{
-		token: t,
-		msg:   fmt.Sprintf("Undefined property '%s'.", t.Lexeme),
+		span: token.SpanOf(t),
+		code: diag.UndefinedProperty,
+		msg:  fmt.Sprintf("Undefined property '%s'.", t.Lexeme),
 	})
 }
 
@@ -0,0 +1,306 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	. "github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/token"
+	"github.com/taki-mekhalfa/golox/value"
+)
+
+// Step tells the debugger where to pause next when the execution resumes
+type Step int
+
+const (
+	// Resume pauses at the next breakpoint
+	Resume Step = iota
+	// StepIn pauses at the next statement
+	StepIn
+	// StepOver pauses at the next statement of the current function
+	// or of its callers, the calls it makes are run through
+	StepOver
+	// StepOut pauses at the next statement of the caller
+	StepOut
+	// Terminate stops the execution with a Cancelled error
+	Terminate
+)
+
+// Debugger pauses the execution of the interpreter it is set on before
+// the statements at breakpoints and after steps. a statement nested in
+// one starting on the same line is not a place to pause at, the line
+// was already paused at.
+type Debugger struct {
+	// Paused is called on the goroutine running the script when the
+	// execution pauses, it returns how to resume. the state of the
+	// execution can be inspected through p until it returns.
+	Paused func(p *Pause) Step
+	// Compile scans, parses and resolves an expression nested in the
+	// scopes declaring names, the outermost first, for Pause.Evaluate
+	Compile func(code string, scopes [][]string) (Expr, error)
+	// Step is how the execution goes on until the next pause,
+	// StepIn pauses at the first statement
+	Step Step
+
+	// mu guards the breakpoints, which are set while the script runs
+	mu          sync.Mutex
+	breakpoints map[string]map[int]bool
+	// abs caches the absolute paths of the files
+	abs map[string]string
+	// interrupt is set to pause at the next statement
+	interrupt int32
+
+	// stack holds the statements being executed, the innermost last
+	stack []active
+	// depth is the number of calls at the last pause
+	depth int
+	// evaluating is set while an expression is evaluated in
+	// a paused frame, the debugger doesn't pause in it
+	evaluating bool
+}
+
+// active is a statement being executed
+type active struct {
+	stmt  Stmt
+	file  string
+	env   *environment
+	depth int
+}
+
+// SetBreakpoints replaces the lines of file to pause at
+func (d *Debugger) SetBreakpoints(file string, lines []int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.breakpoints == nil {
+		d.breakpoints = map[string]map[int]bool{}
+	}
+	file = d.absolute(file)
+	d.breakpoints[file] = map[int]bool{}
+	for _, line := range lines {
+		d.breakpoints[file][line] = true
+	}
+}
+
+// Interrupt pauses the execution at the next statement,
+// it can be called from any goroutine
+func (d *Debugger) Interrupt() {
+	atomic.StoreInt32(&d.interrupt, 1)
+}
+
+// absolute returns the absolute path of file, d.mu is held
+func (d *Debugger) absolute(file string) string {
+	if d.abs == nil {
+		d.abs = map[string]string{}
+	}
+	path, ok := d.abs[file]
+	if !ok {
+		path = file
+		if abs, err := filepath.Abs(file); err == nil && file != "" {
+			path = abs
+		}
+		d.abs[file] = path
+	}
+	return path
+}
+
+func (d *Debugger) isBreakpoint(file string, line int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.breakpoints[d.absolute(file)][line]
+}
+
+// enter is called before a statement is executed and pauses if it has
+// to, the returned function is called once the statement is executed
+func (d *Debugger) enter(i *Interpreter, stmt Stmt) (exit func()) {
+	if d.evaluating {
+		return func() {}
+	}
+
+	depth, line := len(i.frames), stmt.Position().Line
+	nested := false
+	if n := len(d.stack); n > 0 {
+		enclosing := d.stack[n-1]
+		nested = enclosing.depth == depth && enclosing.file == i.File && enclosing.stmt.Position().Line == line
+	}
+	d.stack = append(d.stack, active{stmt: stmt, file: i.File, env: i.env, depth: depth})
+	exit = func() { d.stack = d.stack[:len(d.stack)-1] }
+
+	// an interrupt pauses at any statement, a loop can run on a single line
+	interrupted := atomic.SwapInt32(&d.interrupt, 0) == 1
+	if nested && !interrupted {
+		return exit
+	}
+	breakpoint := !nested && d.isBreakpoint(i.File, line)
+	switch {
+	case interrupted, breakpoint:
+	case d.Step == StepIn:
+	case d.Step == StepOver && depth <= d.depth:
+	case d.Step == StepOut && depth < d.depth:
+	default:
+		return exit
+	}
+
+	d.depth = depth
+	d.Step = d.Paused(&Pause{i: i, d: d, Breakpoint: breakpoint, Interrupted: interrupted})
+	if d.Step == Terminate {
+		panic(runtimeError{span: stmt.Position(), code: diag.Cancelled, msg: "Execution cancelled: terminated by the debugger."})
+	}
+	return exit
+}
+
+// Pause is the state of an execution paused by a debugger
+type Pause struct {
+	i *Interpreter
+	d *Debugger
+	// Breakpoint reports whether the execution paused at a breakpoint
+	// and Interrupted whether it was asked to pause by Interrupt
+	Breakpoint  bool
+	Interrupted bool
+}
+
+// StackFrame is a call of a paused execution
+type StackFrame struct {
+	// Function is the name of the called function in traces,
+	// <script> for the script and <module name> for modules
+	Function string
+	File     string
+	// Span is the span of the statement being executed
+	Span token.Span
+}
+
+// Stack returns the calls being executed, the innermost first
+func (p *Pause) Stack() []StackFrame {
+	var stack []StackFrame
+	for _, a := range p.frames() {
+		name := "<script>"
+		if a.depth > 0 {
+			name = p.i.frames[a.depth-1].name
+		}
+		stack = append(stack, StackFrame{Function: name, File: a.file, Span: a.stmt.Position()})
+	}
+	return stack
+}
+
+// frames returns the innermost statement of each call, the
+// calls made by natives and the host have no statement
+func (p *Pause) frames() []active {
+	var frames []active
+	for j := len(p.d.stack) - 1; j >= 0; j-- {
+		a := p.d.stack[j]
+		if len(frames) == 0 || a.depth < frames[len(frames)-1].depth {
+			frames = append(frames, a)
+		}
+	}
+	return frames
+}
+
+// Variable is a variable of a scope or a member of a value
+type Variable struct {
+	Name  string
+	Value interface{}
+}
+
+// Scope is an environment of a call
+type Scope struct {
+	// Name is Locals for the innermost scope,
+	// Enclosing for the others and Globals
+	Name      string
+	Variables []Variable
+}
+
+// Scopes returns the scopes of the frame at index of the stack,
+// from the innermost to the globals of the script or module
+func (p *Pause) Scopes(frame int) []Scope {
+	frames := p.frames()
+	if frame < 0 || frame >= len(frames) {
+		return nil
+	}
+	var scopes []Scope
+	for env := frames[frame].env; ; env = env.parent {
+		if env == env.globals {
+			return append(scopes, Scope{Name: "Globals", Variables: variables(env.values)})
+		}
+		name := "Enclosing"
+		if len(scopes) == 0 {
+			name = "Locals"
+		}
+		scopes = append(scopes, Scope{Name: name, Variables: variables(env.values)})
+	}
+}
+
+func variables(values map[string]interface{}) []Variable {
+	vars := make([]Variable, 0, len(values))
+	for name, v := range values {
+		vars = append(vars, Variable{Name: name, Value: v})
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+// Members returns the elements of a list, the entries of a map
+// and the fields of an instance, nil for the other values
+func Members(v interface{}) []Variable {
+	switch v := v.(type) {
+	case *value.List:
+		members := make([]Variable, len(v.Elements))
+		for i, element := range v.Elements {
+			members[i] = Variable{Name: fmt.Sprintf("[%d]", i), Value: element}
+		}
+		return members
+	case *value.Map:
+		var members []Variable
+		for _, key := range v.Keys() {
+			entry, _ := v.Get(key)
+			members = append(members, Variable{Name: value.Stringify(key), Value: entry})
+		}
+		return members
+	case *instance:
+		return variables(v.properties)
+	}
+	return nil
+}
+
+// Evaluate evaluates an expression in the environment of the frame at
+// index of the stack, the debugger doesn't pause in the calls it makes
+func (p *Pause) Evaluate(frame int, code string) (result interface{}, err error) {
+	frames := p.frames()
+	if frame < 0 || frame >= len(frames) {
+		return nil, fmt.Errorf("No frame %d.", frame)
+	}
+	if p.d.Compile == nil {
+		return nil, errors.New("The debugger can't compile expressions.")
+	}
+
+	// the resolver gets the names of the scopes
+	// enclosing the frame, the globals excluded
+	env := frames[frame].env
+	var scopes [][]string
+	for e := env; e != e.globals; e = e.parent {
+		names := make([]string, 0, len(e.values))
+		for name := range e.values {
+			names = append(names, name)
+		}
+		scopes = append([][]string{names}, scopes...)
+	}
+	expr, err := p.d.Compile(code, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, depth := p.i.env, len(p.i.frames)
+	p.i.env = env
+	p.d.evaluating = true
+	defer func() {
+		p.i.env = previous
+		p.d.evaluating = false
+		if diags, ok := p.i.asDiagnostic(recover(), depth); ok {
+			result, err = nil, diags
+		}
+	}()
+	return p.i.evaluateExpr(expr), nil
+}
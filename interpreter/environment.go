@@ -9,37 +9,74 @@ func (i *Interpreter) Resolve(expr ast.Expr, distance int) {
 func (i *Interpreter) lookUp(expr ast.Expr, name string) (interface{}, bool) {
 	dist, ok := i.scopeDists[expr]
 	if ok {
-		env := i.env
-		for i := 0; i < dist; i++ {
-			env = env.parent
+		return i.env.ancestor(dist).get(name)
+	}
+	// unresolved variables live in the global scope
+	// of the module being executed
+	return i.env.globals.get(name)
+}
+
+// assign sets the variable expr refers to and
+// reports whether the variable is defined
+func (i *Interpreter) assign(expr ast.Expr, name string, v interface{}) bool {
+	// unresolved variables live in the global scope
+	// of the module being executed
+	env := i.env.globals
+	if dist, ok := i.scopeDists[expr]; ok {
+		env = i.env.ancestor(dist)
+	}
+	if _, ok := env.values[name]; !ok {
+		// assigning a built-in defines a global shadowing it
+		if env != env.globals || env.parent == nil {
+			return false
+		}
+		if _, ok := env.parent.values[name]; !ok {
+			return false
 		}
-		return env.get(name)
 	}
-	return i.globals.get(name)
+	env.values[name] = v
+	return true
 }
 
 type environment struct {
 	values map[string]interface{}
 	parent *environment
+	// globals is the global environment this environment is nested in
+	globals *environment
 }
 
 func newEnvironment(parent *environment) *environment {
-	return &environment{
+	env := &environment{
 		values: map[string]interface{}{},
 		parent: parent,
 	}
+	if parent != nil {
+		env.globals = parent.globals
+	} else {
+		env.globals = env
+	}
+	return env
 }
 
-func (e *environment) define(name string, value interface{}) {
-	e.values[name] = value
+// newGlobalEnvironment creates the global environment of a module,
+// builtins are looked up in it when a global is not defined.
+func newGlobalEnvironment(builtins *environment) *environment {
+	env := newEnvironment(builtins)
+	env.globals = env
+	return env
 }
 
-func (e *environment) assign(name string, value interface{}) {
-	if _, ok := e.values[name]; ok {
-		e.values[name] = value
-		return
+// ancestor returns the environment dist hops up the parent chain
+func (e *environment) ancestor(dist int) *environment {
+	env := e
+	for i := 0; i < dist; i++ {
+		env = env.parent
 	}
-	e.parent.assign(name, value)
+	return env
+}
+
+func (e *environment) define(name string, value interface{}) {
+	e.values[name] = value
 }
 
 func (e *environment) get(name string) (interface{}, bool) {
@@ -0,0 +1,24 @@
+package interpreter
+
+import (
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/token"
+)
+
+// thrown is panicked by a 'throw' statement to bubble the
+// thrown value up to the closest enclosing 'try'
+type thrown struct {
+	span  token.Span
+	value interface{}
+}
+
+// check turns an error returned by a value's operation into a runtime error
+func check(at token.Span, err error) {
+	if err != nil {
+		panic(runtimeError{
+			span: at,
+			code: diag.CodeOf(err),
+			msg:  err.Error(),
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/token"
+	"github.com/taki-mekhalfa/golox/value"
+)
+
+// asDiagnostic converts a recovered runtime error or uncaught
+// thrown value, followed by what caused it, other panics are
+// propagated. the frames above depth are unwound into the trace
+// of the error.
+func (i *Interpreter) asDiagnostic(err interface{}, depth int) (diag.List, bool) {
+	var d diag.Diagnostic
+	var causes diag.List
+	switch err := err.(type) {
+	case nil:
+		return nil, false
+	case runtimeError:
+		d = diag.New(diag.Runtime, err.span, err.code, err.msg)
+		d.Trace = i.unwind(err.span, depth)
+		causes = err.causes
+	case thrown:
+		d = diag.New(diag.Runtime, err.span, diag.UncaughtException, fmt.Sprintf("Uncaught exception: %s.", value.Stringify(err.value)))
+		d.Trace = i.unwind(err.span, depth)
+		if loxErr, ok := err.value.(*value.Error); ok {
+			d.Message = loxErr.Message
+			if loxErr.Code != "" {
+				d.Code = loxErr.Code
+			}
+			causes = loxErr.Causes
+		}
+	default:
+		panic(err)
+	}
+	return append(diag.List{d}, causes...), true
+}
+
+// unwind pops the frames above depth and returns them as the trace
+// of an error raised at the given span, followed by the frame
+// of the script when it is not called from the host.
+func (i *Interpreter) unwind(at token.Span, depth int) []diag.Frame {
+	var trace []diag.Frame
+	for j := len(i.frames) - 1; j >= depth; j-- {
+		trace = append(trace, diag.Frame{Function: i.frames[j].name, File: at.File, Line: at.Line})
+		at = i.frames[j].at
+	}
+	i.frames = i.frames[:depth]
+	if at.Line != 0 {
+		trace = append(trace, diag.Frame{Function: "<script>", File: at.File, Line: at.Line})
+	}
+	return trace
+}
+
+// Define defines a global variable, native functions
+// become callable from scripts.
+func (i *Interpreter) Define(name string, v interface{}) {
+	if n, ok := v.(*value.Native); ok {
+		v = native{n}
+	}
+	i.globals.define(name, v)
+}
+
+// IsValue reports whether v is a value scripts can hold: nil, a bool,
+// a float64, a string, a list, a map, an error, a native function or a
+// function, class, instance or module of an interpreter
+func IsValue(v interface{}) bool {
+	switch v.(type) {
+	case nil, bool, float64, string, *value.List, *value.Map, *value.Error, *value.Native,
+		*function, *class, *instance, *module, native, namespace:
+		return true
+	}
+	return false
+}
+
+// Global returns the value of a global variable or built-in function
+func (i *Interpreter) Global(name string) (interface{}, bool) {
+	return i.globals.get(name)
+}
+
+// Globals returns the global variables of the
+// script, the built-in functions are left out
+func (i *Interpreter) Globals() map[string]interface{} {
+	globals := make(map[string]interface{}, len(i.globals.values))
+	for name, v := range i.globals.values {
+		globals[name] = v
+	}
+	return globals
+}
+
+// Call calls a function, method or class with args from the host.
+// errors raised by the call are returned as a diag.List.
+func (i *Interpreter) Call(callee interface{}, args []interface{}) (result interface{}, err error) {
+	return i.CallContext(context.Background(), callee, args)
+}
+
+// CallContext is like Call but stops when ctx is done
+func (i *Interpreter) CallContext(ctx context.Context, callee interface{}, args []interface{}) (result interface{}, err error) {
+	c, ok := callee.(callable)
+	if !ok {
+		return nil, diag.List{{Phase: diag.Runtime, Code: diag.NotCallable, Message: "Can only call functions and classes."}}
+	}
+	if len(args) != c.arity() {
+		return nil, diag.List{{
+			Phase:   diag.Runtime,
+			Code:    diag.ArityMismatch,
+			Message: fmt.Sprintf("Expected %d arguments, but got %d.", c.arity(), len(args)),
+		}}
+	}
+
+	defer i.begin(ctx)()
+	depth := len(i.frames)
+	defer func() {
+		if diags, ok := i.asDiagnostic(recover(), depth); ok {
+			result, err = nil, diags
+		}
+	}()
+	// the host has no call site, errors raised
+	// by the call itself are reported at line 0
+	return c.call(i, token.Span{}, args), nil
+}
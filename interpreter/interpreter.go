@@ -1,51 +1,151 @@
 package interpreter
 
 import (
+	"context"
 	"fmt"
 
 	. "github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
 	"github.com/taki-mekhalfa/golox/token"
+	"github.com/taki-mekhalfa/golox/value"
 )
 
 type runtimeError struct {
-	token token.Token
-	msg   string
+	span token.Span
+	code diag.Code
+	msg  string
+	// causes are reported after the error, such as
+	// the diagnostics of a module that failed to load
+	causes diag.List
 }
 
 type Interpreter struct {
-	Error      func(line int, errMessage string)
-	ErrorCount int
+	// File is the path of the script being interpreted,
+	// imported modules are first looked up relative to it.
+	File string
+	// SearchPath lists the directories where imported modules
+	// are looked up when they are not found relative to the importer.
+	SearchPath []string
+	// Load scans, parses and resolves the source of the module imported from path
+	Load func(path, src string) ([]Stmt, error)
+	// Console holds the Stdout the 'print' statement writes
+	// to and the Stdin readLine and input read from
+	value.Console
+	// MaxDepth is the maximum number of nested calls, deeper calls
+	// raise a stack overflow error. value.DefaultMaxDepth is used when it is 0.
+	// the calls are nested on the goroutine's stack, a depth too high
+	// lets scripts exhaust it and crash the program.
+	MaxDepth int
+	// MaxSteps bounds the number of loop iterations and calls,
+	// MaxAllocations the number of lists, maps, strings, instances
+	// and functions created by an execution, 0 means unbounded.
+	// exceeding them raises an error scripts can't catch.
+	MaxSteps       int
+	MaxAllocations int
+	// Debugger pauses the execution at breakpoints and steps when set
+	Debugger *Debugger
+	// Profiler records the calls of the Lox functions when set
+	Profiler *Profiler
+
 	env        *environment
+	builtins   *environment
 	globals    *environment
 	scopeDists map[Expr]int
+
+	// modules caches the imported modules by their absolute path
+	modules map[string]*module
+	// loading is the chain of modules being imported
+	loading []string
+	// frames are the calls being executed
+	frames []frame
+
+	// ctx is the context of the execution, nil when none is running
+	ctx         context.Context
+	steps       int
+	allocations int
 }
 
 func (i *Interpreter) Init() {
+	// holds the built-in functions shared by all modules
+	i.builtins = newEnvironment(nil)
+	for _, n := range value.Natives {
+		i.builtins.define(n.Name, native{n})
+	}
+	for _, n := range i.Console.Natives() {
+		i.builtins.define(n.Name, native{n})
+	}
+	for _, n := range value.Namespaces {
+		i.builtins.define(n.Name, namespace{n})
+	}
 	// tracks the global scope
-	i.globals = newEnvironment(nil)
+	i.globals = newGlobalEnvironment(i.builtins)
 	// starts up from the global scope and tracks the
 	// current scope when entering/exiting scopes
 	i.env = i.globals
-	i.globals.define("clock", clockFn)
 	i.scopeDists = make(map[Expr]int)
+	i.modules = make(map[string]*module)
 }
 
 func (i *Interpreter) VisitClass(c *Class) interface{} {
-	class := newClass(c.Name.Lexeme)
-	i.env.define(c.Name.Lexeme, class)
+	var superclass *class
+	if c.Superclass != nil {
+		var ok bool
+		superclass, ok = i.evaluateExpr(c.Superclass).(*class)
+		if !ok {
+			panic(runtimeError{
+				span: token.SpanOf(c.Superclass.Token),
+				code: diag.TypeError,
+				msg:  "Superclass must be a class.",
+			})
+		}
+	}
+
+	i.env.define(c.Name.Lexeme, nil)
+
+	// methods of a subclass close on an environment
+	// binding "super" to the superclass
+	previous := i.env
+	if superclass != nil {
+		i.env = newEnvironment(i.env)
+		i.env.define("super", superclass)
+	}
+
+	class := newClass(c.Name.Lexeme, superclass)
 	for _, method := range c.Methods {
-		class.methods[method.Name.Lexeme] = &function{declaration: method, closure: i.env}
+		i.alloc(method.Position())
+		class.methods[method.Name.Lexeme] = &function{declaration: method, closure: i.env, class: c.Name.Lexeme}
 	}
+
+	i.env = previous
+	i.env.define(c.Name.Lexeme, class)
 	return nil
 }
 
+// object is implemented by the values having properties
+type object interface {
+	get(token.Token) interface{}
+}
+
 func (i *Interpreter) VisitGet(g *Get) interface{} {
 	accessed := i.evaluateExpr(g.Object)
-	object, ok := accessed.(*instance)
+	if err, ok := accessed.(*value.Error); ok {
+		property, ok := err.Property(g.Property.Lexeme)
+		if !ok {
+			panic(runtimeError{
+				span: token.SpanOf(g.Property),
+				code: diag.UndefinedProperty,
+				msg:  fmt.Sprintf("Undefined property '%s'.", g.Property.Lexeme),
+			})
+		}
+		return property
+	}
+
+	object, ok := accessed.(object)
 	if !ok {
 		panic(runtimeError{
-			token: g.Property,
-			msg:   fmt.Sprintf("Only instances have properties"),
+			span: token.SpanOf(g.Property),
+			code: diag.TypeError,
+			msg:  "Only instances have properties.",
 		})
 	}
 
@@ -57,12 +157,14 @@ func (i *Interpreter) VisitSet(s *Set) interface{} {
 	object, ok := accessed.(*instance)
 	if !ok {
 		panic(runtimeError{
-			token: s.Property,
-			msg:   fmt.Sprintf("Only instances have fields"),
+			span: token.SpanOf(s.Property),
+			code: diag.TypeError,
+			msg:  "Only instances have fields.",
 		})
 	}
-	object.set(s.Property, i.evaluateExpr(s.Value))
-	return nil
+	v := i.evaluateExpr(s.Value)
+	object.set(s.Property, v)
+	return v
 }
 
 func (i *Interpreter) VisitThis(this *This) interface{} {
@@ -70,23 +172,240 @@ func (i *Interpreter) VisitThis(this *This) interface{} {
 	v, defined := i.lookUp(this, this.Keyword.Lexeme)
 	if !defined {
 		panic(runtimeError{
-			token: this.Keyword,
-			msg:   fmt.Sprintf("'this' is undefined (this should not happen)."),
+			span: token.SpanOf(this.Keyword),
+			code: diag.UndefinedVariable,
+			msg:  fmt.Sprintf("'this' is undefined (this should not happen)."),
+		})
+	}
+	return v
+}
+
+func (i *Interpreter) VisitSuper(s *Super) interface{} {
+	// "super" is resolved like a var, "this" always lives
+	// in the environment right inside the one holding "super"
+	dist := i.scopeDists[s]
+	superclass, _ := i.env.ancestor(dist).get("super")
+	object, _ := i.env.ancestor(dist - 1).get("this")
+
+	method, ok := superclass.(*class).findMethod(s.Method.Lexeme)
+	if !ok {
+		panic(runtimeError{
+			span: token.SpanOf(s.Method),
+			code: diag.UndefinedProperty,
+			msg:  fmt.Sprintf("Undefined property '%s'.", s.Method.Lexeme),
+		})
+	}
+	return method.bind(object.(*instance))
+}
+
+func (i *Interpreter) VisitList(l *List) interface{} {
+	elements := make([]interface{}, 0, len(l.Elements))
+	for _, element := range l.Elements {
+		elements = append(elements, i.evaluateExpr(element))
+	}
+	i.alloc(l.Position())
+	return &value.List{Elements: elements}
+}
+
+func (i *Interpreter) VisitMap(m *Map) interface{} {
+	i.alloc(m.Position())
+	d := value.NewMap()
+	for j := range m.Keys {
+		key := i.evaluateExpr(m.Keys[j])
+		check(m.Keys[j].Position(), d.Set(key, i.evaluateExpr(m.Values[j])))
+	}
+	return d
+}
+
+func (i *Interpreter) VisitIndex(index *Index) interface{} {
+	object := i.evaluateExpr(index.Object)
+	key := i.evaluateExpr(index.Index)
+	var v interface{}
+	var err error
+	switch object := object.(type) {
+	case *value.List:
+		v, err = object.Get(key)
+	case *value.Map:
+		v, err = object.Get(key)
+	case string:
+		v, err = value.StringIndex(object, key)
+	default:
+		panic(runtimeError{
+			span: index.Position(),
+			code: diag.TypeError,
+			msg:  "Only lists, maps and strings can be indexed.",
+		})
+	}
+	check(index.Position(), err)
+	return v
+}
+
+func (i *Interpreter) VisitSlice(s *Slice) interface{} {
+	object := i.evaluateExpr(s.Object)
+	var start, end interface{}
+	if s.Start != nil {
+		start = i.evaluateExpr(s.Start)
+	}
+	if s.End != nil {
+		end = i.evaluateExpr(s.End)
+	}
+
+	var slice interface{}
+	var err error
+	switch object := object.(type) {
+	case *value.List:
+		slice, err = object.Slice(start, end)
+	case string:
+		slice, err = value.StringSlice(object, start, end)
+	default:
+		panic(runtimeError{
+			span: s.Position(),
+			code: diag.TypeError,
+			msg:  "Only lists and strings can be sliced.",
+		})
+	}
+	check(s.Position(), err)
+	i.alloc(s.Position())
+	return slice
+}
+
+func (i *Interpreter) VisitSetIndex(s *SetIndex) interface{} {
+	object := i.evaluateExpr(s.Object)
+	key := i.evaluateExpr(s.Index)
+	v := i.evaluateExpr(s.Value)
+	switch object := object.(type) {
+	case *value.List:
+		check(s.Position(), object.Set(key, v))
+	case *value.Map:
+		check(s.Position(), object.Set(key, v))
+	case string:
+		panic(runtimeError{
+			span: s.Position(),
+			code: diag.TypeError,
+			msg:  "Strings can't be modified.",
+		})
+	default:
+		panic(runtimeError{
+			span: s.Position(),
+			code: diag.TypeError,
+			msg:  "Only lists and maps can be indexed.",
 		})
 	}
 	return v
 }
 
 func (i *Interpreter) VisitWhile(while *While) interface{} {
-	for truthness(i.evaluateExpr(while.Condition)) {
-		i.evaluateStmt(while.Body)
+	for value.Truthy(i.evaluateExpr(while.Condition)) {
+		if broke := i.evaluateLoopBody(while.Body); broke {
+			break
+		}
+		if while.Increment != nil {
+			i.evaluateExpr(while.Increment)
+		}
+		i.tick(while.Position())
 	}
 
 	return nil
 }
 
+// evaluateLoopBody executes one iteration of a loop body and
+// reports whether the loop was exited with a 'break'
+func (i *Interpreter) evaluateLoopBody(body Stmt) (broke bool) {
+	defer func() {
+		err := recover()
+		switch err.(type) {
+		case nil:
+		case break_:
+			broke = true
+		case continue_:
+		default:
+			// not a loop control flow, panic again to not silently hide it
+			panic(err)
+		}
+	}()
+
+	i.evaluateStmt(body)
+	return
+}
+
+func (i *Interpreter) VisitThrow(t *Throw) interface{} {
+	v := i.evaluateExpr(t.Value)
+	if err, ok := v.(*value.Error); ok && err.Line == 0 {
+		err.Line = t.Keyword.Line
+	}
+	panic(thrown{span: t.Position(), value: v})
+}
+
+func (i *Interpreter) VisitTry(t *Try) interface{} {
+	if t.Finally != nil {
+		// the finally block runs however we leave the try statement,
+		// even when returning or breaking out of it.
+		defer i.evaluateStmt(t.Finally)
+	}
+
+	if t.Catch == nil {
+		i.evaluateStmt(t.Body)
+		return nil
+	}
+
+	caught, ok := i.evaluateTryBody(t.Body)
+	if !ok {
+		return nil
+	}
+
+	previous := i.env
+	defer func() {
+		i.env = previous
+	}()
+
+	// bind the caught value in a new environment
+	// surrounding the catch block
+	i.env = newEnvironment(i.env)
+	i.env.define(t.CatchParam.Lexeme, caught)
+	i.evaluateStmt(t.Catch)
+	return nil
+}
+
+// evaluateTryBody executes the body of a try statement and returns
+// the value thrown inside of it, if any.
+// runtime errors are caught as error values.
+func (i *Interpreter) evaluateTryBody(body Stmt) (caught interface{}, ok bool) {
+	// save current environment and calls to recover back when catching
+	previous, depth := i.env, len(i.frames)
+	defer func() {
+		err := recover()
+		switch err := err.(type) {
+		case nil:
+			return
+		case thrown:
+			caught = err.value
+		case runtimeError:
+			if !diag.Catchable(err.code) {
+				panic(err)
+			}
+			caught = &value.Error{Message: err.msg, Line: err.span.Line, Code: err.code, Causes: err.causes}
+		default:
+			// return, break and continue are not errors, let them through
+			panic(err)
+		}
+		i.env, i.frames = previous, i.frames[:depth]
+		ok = true
+	}()
+
+	i.evaluateStmt(body)
+	return
+}
+
+func (i *Interpreter) VisitBreak(b *Break) interface{} {
+	panic(break_{})
+}
+
+func (i *Interpreter) VisitContinue(c *Continue) interface{} {
+	panic(continue_{})
+}
+
 func (i *Interpreter) VisitIf(if_ *If) interface{} {
-	if truthness(i.evaluateExpr(if_.Condition)) {
+	if value.Truthy(i.evaluateExpr(if_.Condition)) {
 		return i.evaluateStmt(if_.Then)
 	}
 
@@ -124,7 +443,7 @@ func (i *Interpreter) VisitExprStmt(es *ExprStmt) interface{} {
 }
 
 func (i *Interpreter) VisitPrint(printExpr *Print) interface{} {
-	fmt.Println(fmt.Sprint(i.evaluateExpr(printExpr.Expr)))
+	i.Print(i.evaluateExpr(printExpr.Expr))
 
 	return nil
 }
@@ -132,11 +451,18 @@ func (i *Interpreter) VisitPrint(printExpr *Print) interface{} {
 func (i *Interpreter) VisitFunction(f *Function) interface{} {
 	// define the funciton in the current scope
 	// and allow the function to close on it
+	i.alloc(f.Position())
 	i.env.define(f.Name.Lexeme, &function{declaration: f, closure: i.env})
 
 	return nil
 }
 
+func (i *Interpreter) VisitLambda(l *Lambda) interface{} {
+	// close on the current scope just like a named function
+	i.alloc(l.Position())
+	return &function{declaration: l.Function, closure: i.env}
+}
+
 func (i *Interpreter) VisitVarStmt(var_ *VarStmt) interface{} {
 	if var_.Initializer == nil {
 		i.env.define(var_.Name, nil)
@@ -152,55 +478,59 @@ func (i *Interpreter) VisitBinary(b *Binary) interface{} {
 
 	switch b.Operator.Type {
 	case token.STAR:
-		checkNumberOperands(b.Operator, left, right)
+		checkNumberOperands(b.Position(), left, right)
 		return left.(float64) * right.(float64)
 	case token.SLASH:
-		checkNumberOperands(b.Operator, left, right)
+		checkNumberOperands(b.Position(), left, right)
 		rightNumber := right.(float64)
-		checkIsNotZero(b.Operator, rightNumber)
+		checkIsNotZero(b.Position(), rightNumber)
 		return left.(float64) / rightNumber
 	case token.MINUS:
-		checkNumberOperands(b.Operator, left, right)
+		checkNumberOperands(b.Position(), left, right)
 		return left.(float64) - right.(float64)
 	case token.PLUS:
-		checkOperandsSameType(b.Operator, left, right)
+		checkOperandsSameType(b.Position(), left, right)
 		switch left.(type) {
 		case float64:
 			return left.(float64) + right.(float64)
 		case string:
-
+			i.alloc(b.Position())
 			return left.(string) + right.(string)
 		}
 	case token.GREATER:
-		return left.(float64) > right.(float64)
+		return compare(b.Position(), value.Greater, left, right)
 	case token.GREATER_EQUAL:
-		return left.(float64) >= right.(float64)
+		return compare(b.Position(), value.GreaterEqual, left, right)
 	case token.LESS:
-		return left.(float64) < right.(float64)
+		return compare(b.Position(), value.Less, left, right)
 	case token.LESS_EQUAL:
-		return left.(float64) <= right.(float64)
+		return compare(b.Position(), value.LessEqual, left, right)
 
 	case token.BANG_EQUAL:
-		return left != right
+		return !value.Equal(left, right)
 	case token.EQUAL_EQUAL:
-		return left == right
+		return value.Equal(left, right)
 	}
 
 	// should not happen
 	return nil
 }
 
+// VisitLogical returns the operand deciding the value of the
+// expression, the right one is evaluated only when it decides it
 func (i *Interpreter) VisitLogical(l *Logical) interface{} {
+	left := i.evaluateExpr(l.Left)
 	switch l.Operator.Type {
-	// golang will take care of short-circuiting both operators
 	case token.AND:
-		return truthness(i.evaluateExpr(l.Left)) && truthness(i.evaluateExpr(l.Right))
+		if !value.Truthy(left) {
+			return left
+		}
 	case token.OR:
-		return truthness(i.evaluateExpr(l.Left)) || truthness(i.evaluateExpr(l.Right))
+		if value.Truthy(left) {
+			return left
+		}
 	}
-
-	// should not happen
-	return nil
+	return i.evaluateExpr(l.Right)
 }
 
 func (i *Interpreter) VisitGrouping(g *Grouping) interface{} {
@@ -208,15 +538,14 @@ func (i *Interpreter) VisitGrouping(g *Grouping) interface{} {
 }
 
 func (i *Interpreter) VisitAssign(a *Assign) interface{} {
-	if _, defined := i.lookUp(a, a.Identifier.Lexeme); !defined {
+	v := i.evaluateExpr(a.Value)
+	if !i.assign(a, a.Identifier.Lexeme, v) {
 		panic(runtimeError{
-			token: a.Identifier,
-			msg:   fmt.Sprintf("Undefined variable '" + a.Identifier.Lexeme + "'."),
+			span: token.SpanOf(a.Identifier),
+			code: diag.UndefinedVariable,
+			msg:  fmt.Sprintf("Undefined variable '" + a.Identifier.Lexeme + "'."),
 		})
 	}
-	v := i.evaluateExpr(a.Value)
-	// update the symbol's value
-	i.env.assign(a.Identifier.Lexeme, v)
 	return v
 }
 
@@ -224,8 +553,9 @@ func (i *Interpreter) VisitVar(var_ *Var) interface{} {
 	v, defined := i.lookUp(var_, var_.Token.Lexeme)
 	if !defined {
 		panic(runtimeError{
-			token: var_.Token,
-			msg:   fmt.Sprintf("Undefined variable '" + var_.Token.Lexeme + "'."),
+			span: token.SpanOf(var_.Token),
+			code: diag.UndefinedVariable,
+			msg:  fmt.Sprintf("Undefined variable '" + var_.Token.Lexeme + "'."),
 		})
 	}
 
@@ -237,27 +567,30 @@ func (i *Interpreter) VisitLiteral(l *Literal) interface{} {
 }
 
 func (i *Interpreter) VisitCall(c *Call) interface{} {
-	callee, ok := i.evaluateExpr(c.Callee).(callable)
+	calleeValue := i.evaluateExpr(c.Callee)
+	args := []interface{}{}
+	// evaluate function's args before checking the callee
+	for _, arg := range c.Args {
+		args = append(args, i.evaluateExpr(arg))
+	}
+
+	callee, ok := calleeValue.(callable)
 	if !ok {
 		panic(runtimeError{
-			token: c.ClosingParent,
-			msg:   "Can only call functions and classes.",
+			span: c.Position(),
+			code: diag.NotCallable,
+			msg:  "Can only call functions and classes.",
 		})
 	}
-	if len(c.Args) != callee.arity() {
+	if len(args) != callee.arity() {
 		panic(runtimeError{
-			token: c.ClosingParent,
-			msg:   fmt.Sprintf("Expected %d arguments, but got %d.", callee.arity(), len(c.Args)),
+			span: c.Position(),
+			code: diag.ArityMismatch,
+			msg:  fmt.Sprintf("Expected %d arguments, but got %d.", callee.arity(), len(args)),
 		})
 	}
 
-	args := []interface{}{}
-	// evaluate function's args
-	for _, arg := range c.Args {
-		args = append(args, i.evaluateExpr(arg))
-	}
-
-	return callee.call(i, args)
+	return callee.call(i, c.Position(), args)
 }
 
 func (i *Interpreter) VisitReturn(r *Return) interface{} {
@@ -275,9 +608,9 @@ func (i *Interpreter) VisitUnary(u *Unary) interface{} {
 	v := i.evaluateExpr(u.Expr)
 	switch u.Operator.Type {
 	case token.BANG:
-		return !truthness(v)
+		return !value.Truthy(v)
 	case token.MINUS:
-		checkNumberOperand(u.Operator, v)
+		checkNumberOperand(u.Position(), v)
 		return -v.(float64)
 	}
 
@@ -285,29 +618,17 @@ func (i *Interpreter) VisitUnary(u *Unary) interface{} {
 	return nil
 }
 
-// truthness returns true if v is true and false otherwise.
-// everything is true expect for a boolean false or a <nil>
-func truthness(v interface{}) bool {
-	if v == nil {
-		return false
-	}
-	if boolValue, ok := v.(bool); ok {
-		return boolValue
-	}
-
-	return true
-}
-
-func checkNumberOperand(t token.Token, o interface{}) {
+func checkNumberOperand(at token.Span, o interface{}) {
 	if _, ok := o.(float64); !ok {
 		panic(runtimeError{
-			token: t,
-			msg:   "Operand must be a number.",
+			span: at,
+			code: diag.TypeError,
+			msg:  "Operand must be a number.",
 		})
 	}
 }
 
-func checkNumberOperands(t token.Token, left, right interface{}) {
+func checkNumberOperands(at token.Span, left, right interface{}) {
 	_, leftIsNumber := left.(float64)
 	_, rightIsNumber := right.(float64)
 	if leftIsNumber && rightIsNumber {
@@ -315,32 +636,54 @@ func checkNumberOperands(t token.Token, left, right interface{}) {
 	}
 
 	panic(runtimeError{
-		token: t,
-		msg:   "Operands must be both numbers.",
+		span: at,
+		code: diag.TypeError,
+		msg:  "Operands must be both numbers.",
 	})
 }
 
-func checkOperandsSameType(t token.Token, left, right interface{}) {
-	_, leftIsNumber := left.(float64)
-	_, rightIsNumber := right.(float64)
-	if leftIsNumber == rightIsNumber {
-		return
+// compare compares two numbers or two strings
+func compare(at token.Span, c value.Comparison, left, right interface{}) bool {
+	result, ok := value.Compare(c, left, right)
+	if !ok {
+		panic(runtimeError{
+			span: at,
+			code: diag.TypeError,
+			msg:  "Operands must be two numbers or two strings.",
+		})
+	}
+	return result
+}
+
+// checkOperandsSameType checks the operands of '+' are both numbers or both strings
+func checkOperandsSameType(at token.Span, left, right interface{}) {
+	switch left.(type) {
+	case float64:
+		if _, ok := right.(float64); ok {
+			return
+		}
+	case string:
+		if _, ok := right.(string); ok {
+			return
+		}
 	}
 
 	panic(runtimeError{
-		token: t,
-		msg:   "Operands must be both numbers or both strings.",
+		span: at,
+		code: diag.TypeError,
+		msg:  "Operands must be both numbers or both strings.",
 	})
 }
 
-func checkIsNotZero(t token.Token, n float64) {
+func checkIsNotZero(at token.Span, n float64) {
 	if n != 0 {
 		return
 	}
 
 	panic(runtimeError{
-		token: t,
-		msg:   "Divided by 0.",
+		span: at,
+		code: diag.DivisionByZero,
+		msg:  "Divided by 0.",
 	})
 }
 
@@ -349,28 +692,55 @@ func (i *Interpreter) evaluateExpr(expr Expr) interface{} {
 }
 
 func (i *Interpreter) evaluateStmt(stmt Stmt) interface{} {
+	if i.Debugger != nil {
+		defer i.Debugger.enter(i, stmt)()
+	}
 	return stmt.Accept(i)
 }
 
-func (i *Interpreter) Interpret(stmts []Stmt) {
+// evaluateExprStmt returns the value of a top-level expression statement
+func (i *Interpreter) evaluateExprStmt(es *ExprStmt) interface{} {
+	if i.Debugger != nil {
+		defer i.Debugger.enter(i, es)()
+	}
+	return i.evaluateExpr(es.Expr)
+}
+
+// Interpret interprets stmts, the returned error is
+// a diag.List holding the error the program stopped at.
+func (i *Interpreter) Interpret(stmts []Stmt) error {
+	return i.InterpretContext(context.Background(), stmts)
+}
+
+// InterpretContext is like Interpret but stops with a Cancelled
+// error when ctx is done, it is checked at loop back-edges and calls.
+func (i *Interpreter) InterpretContext(ctx context.Context, stmts []Stmt) error {
+	_, err := i.RunContext(ctx, stmts)
+	return err
+}
+
+// Run interprets stmts and returns the value of the last
+// statement if it is an expression statement.
+func (i *Interpreter) Run(stmts []Stmt) (result interface{}, err error) {
+	return i.RunContext(context.Background(), stmts)
+}
+
+// RunContext is like Run but stops when ctx is done
+func (i *Interpreter) RunContext(ctx context.Context, stmts []Stmt) (result interface{}, err error) {
+	defer i.begin(ctx)()
 	defer func() {
-		err := recover()
-		if err == nil {
-			return
+		if diags, ok := i.asDiagnostic(recover(), 0); ok {
+			result, err = nil, diags
 		}
-		if runtimeErr, ok := err.(runtimeError); ok {
-			i.ErrorCount++
-			i.Error(runtimeErr.token.Line, runtimeErr.msg)
-			return
-		}
-		panic(err)
 	}()
 
 	for _, stmt := range stmts {
+		result = nil
+		if es, ok := stmt.(*ExprStmt); ok {
+			result = i.evaluateExprStmt(es)
+			continue
+		}
 		i.evaluateStmt(stmt)
 	}
-}
-
-func (i *Interpreter) ResetErrors() {
-	i.ErrorCount = 0
+	return result, nil
 }
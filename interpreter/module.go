@@ -0,0 +1,140 @@
+package interpreter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/token"
+	"github.com/taki-mekhalfa/golox/value"
+)
+
+// module is the namespace object an imported file is bound to,
+// its properties are the top-level definitions of the file
+type module struct {
+	name string
+	env  *environment
+}
+
+// String implements fmt.Stringer
+func (m *module) String() string {
+	return m.name + " module"
+}
+
+func (m *module) get(t token.Token) interface{} {
+	// builtins live in the parent of the module's
+	// global environment and are not exported
+	if v, ok := m.env.values[t.Lexeme]; ok {
+		return v
+	}
+	panic(runtimeError{
+		span: token.SpanOf(t),
+		code: diag.UndefinedProperty,
+		msg:  fmt.Sprintf("Undefined property '%s' in module '%s'.", t.Lexeme, m.name),
+	})
+}
+
+func (i *Interpreter) VisitImport(imp *Import) interface{} {
+	i.env.define(imp.Name.Lexeme, i.importModule(imp))
+	return nil
+}
+
+// importModule loads, executes and caches the module imported by imp.
+// a module is only executed the first time it is imported.
+func (i *Interpreter) importModule(imp *Import) *module {
+	if i.Load == nil {
+		panic(runtimeError{
+			span: imp.Position(),
+			code: diag.ImportError,
+			msg:  "Modules can't be imported, no loader was set up.",
+		})
+	}
+
+	path := i.findModule(imp)
+	if m, ok := i.modules[path]; ok {
+		return m
+	}
+
+	if len(i.loading) == 0 && i.File != "" {
+		// the importing script is the root of the chain of imports
+		if root, err := filepath.Abs(i.File); err == nil {
+			i.loading = []string{root}
+			defer func() { i.loading = nil }()
+		}
+	}
+
+	for j, loading := range i.loading {
+		if loading == path {
+			cycle := append(append([]string{}, i.loading[j:]...), path)
+			for k := range cycle {
+				cycle[k] = filepath.Base(cycle[k])
+			}
+			panic(runtimeError{
+				span: imp.Position(),
+				code: diag.ImportError,
+				msg:  fmt.Sprintf("Import cycle detected: %s.", strings.Join(cycle, " -> ")),
+			})
+		}
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		panic(runtimeError{
+			span: imp.Position(),
+			code: diag.ImportError,
+			msg:  fmt.Sprintf("Could not read module '%s'.", imp.Path),
+		})
+	}
+	stmts, err := i.Load(path, string(src))
+	if err != nil {
+		// the diagnostics of the module are reported after the import
+		if diags, ok := err.(diag.List); ok {
+			panic(runtimeError{
+				span:   imp.Position(),
+				code:   diag.ImportError,
+				msg:    fmt.Sprintf("Could not load module '%s'.", imp.Path),
+				causes: diags,
+			})
+		}
+		panic(runtimeError{
+			span: imp.Position(),
+			code: diag.ImportError,
+			msg:  fmt.Sprintf("Could not load module '%s': %v", imp.Path, err),
+		})
+	}
+
+	// execute the module in its own global environment,
+	// imports inside of it are relative to its own file
+	previousEnv, previousFile := i.env, i.File
+	i.loading = append(i.loading, path)
+	defer func() {
+		i.env, i.File = previousEnv, previousFile
+		i.loading = i.loading[:len(i.loading)-1]
+	}()
+
+	m := &module{name: imp.Name.Lexeme, env: newGlobalEnvironment(i.builtins)}
+	i.env, i.File = m.env, path
+	i.enter(frame{name: "<module " + imp.Name.Lexeme + ">", at: imp.Position()})
+	for _, stmt := range stmts {
+		i.evaluateStmt(stmt)
+	}
+	i.frames = i.frames[:len(i.frames)-1]
+
+	i.modules[path] = m
+	return m
+}
+
+func (i *Interpreter) findModule(imp *Import) string {
+	path, ok := value.FindModule(i.File, imp.Path, i.SearchPath)
+	if !ok {
+		panic(runtimeError{
+			span: imp.Position(),
+			code: diag.ImportError,
+			msg:  fmt.Sprintf("Could not find module '%s'.", imp.Path),
+		})
+	}
+	return path
+}
@@ -0,0 +1,51 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/token"
+	"github.com/taki-mekhalfa/golox/value"
+)
+
+// native adapts a built-in function to the callable interface
+type native struct {
+	*value.Native
+}
+
+func (n native) arity() int { return n.Arity }
+
+func (n native) call(interpreter *Interpreter, at token.Span, args []interface{}) interface{} {
+	v, err := n.Fn(args)
+	if err != nil {
+		panic(runtimeError{
+			span: at,
+			code: diag.CodeOf(err),
+			msg:  err.Error(),
+		})
+	}
+	if n.Allocates(v) {
+		interpreter.alloc(at)
+	}
+	return v
+}
+
+// namespace adapts a built-in namespace to the object interface
+type namespace struct {
+	*value.Namespace
+}
+
+func (n namespace) get(t token.Token) interface{} {
+	v, ok := n.Property(t.Lexeme)
+	if !ok {
+		panic(runtimeError{
+			span: token.SpanOf(t),
+			code: diag.UndefinedProperty,
+			msg:  fmt.Sprintf("Undefined property '%s' in module '%s'.", t.Lexeme, n.Name),
+		})
+	}
+	if fn, ok := v.(*value.Native); ok {
+		return native{fn}
+	}
+	return v
+}
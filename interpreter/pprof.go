@@ -0,0 +1,115 @@
+package interpreter
+
+import (
+	"compress/gzip"
+	"io"
+	"time"
+)
+
+// WritePprof writes the profile in the gzipped protocol buffer format
+// of pprof, each function is a location at its declaration. the samples
+// hold the calls, the nanoseconds and the allocations of their stacks.
+// see https://github.com/google/pprof/blob/main/proto/profile.proto
+func (p *Profiler) WritePprof(w io.Writer) error {
+	indexes := map[string]int64{"": 0}
+	table := []string{""}
+	str := func(s string) int64 {
+		index, ok := indexes[s]
+		if !ok {
+			index = int64(len(table))
+			indexes[s] = index
+			table = append(table, s)
+		}
+		return index
+	}
+
+	var profile protobuf
+	for _, t := range [][2]string{{"calls", "count"}, {"wall", "nanoseconds"}, {"alloc_objects", "count"}} {
+		var valueType protobuf
+		valueType.int(1, str(t[0]))
+		valueType.int(2, str(t[1]))
+		profile.message(1, valueType)
+	}
+	for _, s := range p.order {
+		var sample protobuf
+		locations := make([]int64, len(s.stack))
+		for i, index := range s.stack {
+			locations[i] = int64(index) + 1
+		}
+		sample.packed(1, locations)
+		sample.packed(2, []int64{int64(s.calls), int64(s.self), int64(s.allocations)})
+		profile.message(2, sample)
+	}
+	// the ids of the locations and of the functions
+	// are the indexes of the functions plus one
+	for i, f := range p.functions {
+		var line, location, function protobuf
+		line.int(1, int64(i)+1)
+		line.int(2, int64(f.Line))
+		location.int(1, int64(i)+1)
+		location.message(4, line)
+		profile.message(4, location)
+
+		function.int(1, int64(i)+1)
+		function.int(2, str(f.Name))
+		function.int(3, str(f.Name))
+		function.int(4, str(f.File))
+		function.int(5, int64(f.Line))
+		profile.message(5, function)
+	}
+	if !p.start.IsZero() {
+		profile.int(9, p.start.UnixNano())
+		profile.int(10, int64(time.Since(p.start)))
+	}
+	// pprof shows the wall time by default
+	profile.int(14, str("wall"))
+	for _, s := range table {
+		profile.bytes(6, []byte(s))
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(profile); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// protobuf encodes the fields of a protocol buffer message
+type protobuf []byte
+
+func (b *protobuf) varint(v uint64) {
+	for v >= 0x80 {
+		*b = append(*b, byte(v)|0x80)
+		v >>= 7
+	}
+	*b = append(*b, byte(v))
+}
+
+// int encodes a varint field, zero values are omitted
+func (b *protobuf) int(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	b.varint(uint64(field) << 3)
+	b.varint(uint64(v))
+}
+
+// bytes encodes a length-delimited field
+func (b *protobuf) bytes(field int, v []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+func (b *protobuf) message(field int, m protobuf) {
+	b.bytes(field, m)
+}
+
+// packed encodes a repeated varint field
+func (b *protobuf) packed(field int, vs []int64) {
+	var packed protobuf
+	for _, v := range vs {
+		packed.varint(uint64(v))
+	}
+	b.bytes(field, packed)
+}
@@ -0,0 +1,175 @@
+package interpreter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/taki-mekhalfa/golox/token"
+)
+
+// Profiler records the calls of the Lox functions and methods run by
+// the interpreter it is set on, the natives are not profiled. the
+// time of a call is wall time, the time spent paused in a debugger
+// included.
+type Profiler struct {
+	// functions are the profiles in the order of their first call
+	functions []*FunctionProfile
+	byKey     map[functionKey]int
+	// samples aggregate the calls by stack, for pprof
+	samples map[string]*sample
+	order   []*sample
+	// stack holds the calls being executed, the innermost last
+	stack []activeCall
+	start time.Time
+}
+
+// FunctionProfile is what was recorded for a function,
+// the functions are keyed by name and declaration
+type FunctionProfile struct {
+	// Name is the name of the function in traces,
+	// Class.method for methods and <fn> for lambdas
+	Name string
+	File string
+	Line int
+	// Calls is the number of calls
+	Calls int
+	// Total is the time spent in the calls, the calls they
+	// made included, and Self the time spent in their own
+	// code. a recursive call is counted once in Total.
+	Total time.Duration
+	Self  time.Duration
+	// Allocations is the number of lists, maps, strings, instances
+	// and functions created by the code of the function itself
+	Allocations int
+}
+
+type functionKey struct {
+	name string
+	file string
+	line int
+}
+
+// sample is what was recorded for a stack of calls
+type sample struct {
+	// stack are the indexes of the functions, the innermost first
+	stack       []int
+	calls       int
+	self        time.Duration
+	allocations int
+}
+
+type activeCall struct {
+	function int
+	sample   *sample
+	start    time.Time
+	// children is the time spent in the calls it made
+	children time.Duration
+}
+
+// enter records the start of a call to f and returns the function
+// recording its end, which is called however the call ends
+func (p *Profiler) enter(f *function) (exit func()) {
+	if p.byKey == nil {
+		p.byKey = map[functionKey]int{}
+		p.samples = map[string]*sample{}
+		p.start = time.Now()
+	}
+
+	at := token.SpanOf(f.declaration.Name)
+	key := functionKey{name: f.name(), file: at.File, line: at.Line}
+	index, ok := p.byKey[key]
+	if !ok {
+		index = len(p.functions)
+		p.byKey[key] = index
+		p.functions = append(p.functions, &FunctionProfile{Name: key.name, File: key.file, Line: key.line})
+	}
+
+	stack := []int{index}
+	for j := len(p.stack) - 1; j >= 0; j-- {
+		stack = append(stack, p.stack[j].function)
+	}
+	var id strings.Builder
+	for _, index := range stack {
+		fmt.Fprintf(&id, "%d/", index)
+	}
+	s, ok := p.samples[id.String()]
+	if !ok {
+		s = &sample{stack: stack}
+		p.samples[id.String()] = s
+		p.order = append(p.order, s)
+	}
+
+	p.stack = append(p.stack, activeCall{function: index, sample: s, start: time.Now()})
+	return p.exit
+}
+
+// exit records the end of the innermost call
+func (p *Profiler) exit() {
+	call := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	elapsed := time.Since(call.start)
+
+	f := p.functions[call.function]
+	f.Calls++
+	f.Self += elapsed - call.children
+	call.sample.calls++
+	call.sample.self += elapsed - call.children
+	if n := len(p.stack); n > 0 {
+		p.stack[n-1].children += elapsed
+	}
+	// the time of a recursive call is in the total of the outermost one
+	recursive := false
+	for _, c := range p.stack {
+		if c.function == call.function {
+			recursive = true
+			break
+		}
+	}
+	if !recursive {
+		f.Total += elapsed
+	}
+}
+
+// alloc counts an allocation made by the innermost call
+func (p *Profiler) alloc() {
+	if n := len(p.stack); n > 0 {
+		call := p.stack[n-1]
+		p.functions[call.function].Allocations++
+		call.sample.allocations++
+	}
+}
+
+// Functions returns the profiles of the functions called,
+// the ones spending the most time in their own code first
+func (p *Profiler) Functions() []FunctionProfile {
+	functions := make([]FunctionProfile, len(p.functions))
+	for i, f := range p.functions {
+		functions[i] = *f
+	}
+	sort.SliceStable(functions, func(i, j int) bool { return functions[i].Self > functions[j].Self })
+	return functions
+}
+
+// WriteTable writes the profiles of the functions
+// as a table sorted by the time spent in their code
+func (p *Profiler) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "calls\tself\tself%\ttotal\tallocs\t function")
+	var all time.Duration
+	for _, f := range p.functions {
+		all += f.Self
+	}
+	for _, f := range p.Functions() {
+		percent := 0.0
+		if all > 0 {
+			percent = 100 * float64(f.Self) / float64(all)
+		}
+		fmt.Fprintf(tw, "%d\t%v\t%.1f%%\t%v\t%d\t %s (%s:%d)\n",
+			f.Calls, f.Self.Round(time.Microsecond), percent, f.Total.Round(time.Microsecond), f.Allocations, f.Name, f.File, f.Line)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,192 @@
+// Package lox embeds golox in Go programs: scripts are evaluated
+// by a VM whose global variables can be read and written from Go,
+// and Go functions can be exposed to scripts and call them back.
+package lox
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/interpreter"
+	"github.com/taki-mekhalfa/golox/resolver"
+	"github.com/taki-mekhalfa/golox/value"
+)
+
+// Value is a Lox value: nil, bool, float64, string, *value.List,
+// *value.Map, *value.Error or a function, class or instance
+// defined by a script, which can only be passed back to the VM.
+// the values given to the VM can also be Go integers and float32,
+// converted to float64, []Value and map[string]Value, converted to
+// lists and maps, the other Go values are rejected with an error.
+type Value = interface{}
+
+// VM runs scripts with the tree-walk interpreter, the globals
+// defined by a script are visible to the scripts evaluated after it.
+type VM struct {
+	interpreter interpreter.Interpreter
+}
+
+// New returns a VM, imported modules are looked up
+// relative to the working directory then in searchPath.
+func New(searchPath ...string) *VM {
+	vm := &VM{}
+	vm.interpreter.Init()
+	vm.interpreter.SearchPath = searchPath
+	vm.interpreter.Load = vm.compile
+	return vm
+}
+
+// Eval runs src and returns the value of its last statement if it
+// is an expression statement, errors are returned as a diag.List.
+func (vm *VM) Eval(src string) (Value, error) {
+	return vm.EvalContext(context.Background(), src)
+}
+
+// EvalContext is like Eval but stops the script when ctx is done,
+// the error is then a diag.List holding a diag.Cancelled diagnostic.
+func (vm *VM) EvalContext(ctx context.Context, src string) (Value, error) {
+	stmts, err := vm.compile("", src)
+	if err != nil {
+		return nil, err
+	}
+	return vm.interpreter.RunContext(ctx, stmts)
+}
+
+// SetOutput sets the writer 'print' writes to, os.Stdout by default
+func (vm *VM) SetOutput(w io.Writer) {
+	vm.interpreter.Stdout = w
+}
+
+// SetInput sets the reader the readLine and input
+// built-in functions read from, os.Stdin by default
+func (vm *VM) SetInput(r io.Reader) {
+	vm.interpreter.Stdin = r
+}
+
+// SetMaxDepth sets the maximum number of nested calls, deeper
+// calls raise a stack overflow error, 0 restores the default.
+func (vm *VM) SetMaxDepth(n int) {
+	vm.interpreter.MaxDepth = n
+}
+
+// SetBudget bounds the number of loop iterations and calls and the number
+// of lists, maps, strings, instances and functions created by each Eval
+// and Call, 0 is unbounded. a script exceeding its budget is stopped with
+// a diag.BudgetExceeded error it can't catch.
+func (vm *VM) SetBudget(steps, allocations int) {
+	vm.interpreter.MaxSteps, vm.interpreter.MaxAllocations = steps, allocations
+}
+
+// DefineFunc defines a global function calling fn, an error returned
+// by fn is raised as a runtime error scripts can catch, as is a result
+// that can't be converted to a Lox value.
+func (vm *VM) DefineFunc(name string, arity int, fn func(args []Value) (Value, error)) {
+	vm.interpreter.Define(name, &value.Native{Name: name, Arity: arity, Fn: func(args []Value) (Value, error) {
+		result, err := fn(args)
+		if err != nil {
+			return nil, err
+		}
+		return convert(result)
+	}})
+}
+
+// GetGlobal returns the value of a global variable or built-in function
+func (vm *VM) GetGlobal(name string) (Value, bool) {
+	return vm.interpreter.Global(name)
+}
+
+// SetGlobal defines or overwrites a global variable, the
+// error is a diag.List when v can't be converted to a Lox value
+func (vm *VM) SetGlobal(name string, v Value) error {
+	v, err := convert(v)
+	if err != nil {
+		return diag.List{{Phase: diag.Runtime, Code: diag.CodeOf(err), Message: err.Error()}}
+	}
+	vm.interpreter.Define(name, v)
+	return nil
+}
+
+// Call calls fn, a function, method or class taken from the VM, with args
+func (vm *VM) Call(fn Value, args ...Value) (Value, error) {
+	return vm.CallContext(context.Background(), fn, args...)
+}
+
+// CallContext is like Call but stops the call when ctx is done
+func (vm *VM) CallContext(ctx context.Context, fn Value, args ...Value) (Value, error) {
+	converted := make([]Value, len(args))
+	for i, arg := range args {
+		v, err := convert(arg)
+		if err != nil {
+			return nil, diag.List{{Phase: diag.Runtime, Code: diag.CodeOf(err), Message: err.Error()}}
+		}
+		converted[i] = v
+	}
+	return vm.interpreter.CallContext(ctx, fn, converted)
+}
+
+// convert converts the Go numbers to Lox numbers, the []Value to lists
+// and the map[string]Value to maps, in the order of their keys. the
+// other Go values can't be held by scripts and are rejected.
+func convert(v Value) (Value, error) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), nil
+	case int8:
+		return float64(v), nil
+	case int16:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint8:
+		return float64(v), nil
+	case uint16:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case float32:
+		return float64(v), nil
+	case []Value:
+		list := &value.List{Elements: make([]interface{}, len(v))}
+		for i, element := range v {
+			converted, err := convert(element)
+			if err != nil {
+				return nil, err
+			}
+			list.Elements[i] = converted
+		}
+		return list, nil
+	case map[string]Value:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		m := value.NewMap()
+		for _, key := range keys {
+			converted, err := convert(v[key])
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key, converted)
+		}
+		return m, nil
+	}
+	if !interpreter.IsValue(v) {
+		return nil, diag.Errorf(diag.NativeError, "Can't convert the Go value of type %T to a Lox value.", v)
+	}
+	return v, nil
+}
+
+// compile scans, parses and resolves the source of file
+func (vm *VM) compile(file, src string) ([]ast.Stmt, error) {
+	return resolver.Compile(file, src, &vm.interpreter)
+}
@@ -0,0 +1,405 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/parser"
+	"github.com/taki-mekhalfa/golox/resolver"
+	"github.com/taki-mekhalfa/golox/scanner"
+	"github.com/taki-mekhalfa/golox/token"
+	"github.com/taki-mekhalfa/golox/value"
+)
+
+// document is an open document and what the analysis of its text found
+type document struct {
+	uri  string
+	text string
+	// lines are the offsets of the lines of text
+	lines []int
+
+	stmts   []ast.Stmt
+	symbols *resolver.Symbols
+	diags   diag.List
+}
+
+// analyze scans, parses and resolves the text of a document. the
+// statements parsed before and after a syntax error are resolved
+// to keep the symbols of the document while it is being edited,
+// but their resolving errors are only reported without syntax errors.
+func analyze(uri, text string) *document {
+	doc := &document{uri: uri, text: text, lines: []int{0}, symbols: &resolver.Symbols{}}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			doc.lines = append(doc.lines, i+1)
+		}
+	}
+
+	scanner := scanner.Scanner{}
+	scanner.Init(text)
+	if err := scanner.Scan(); err != nil {
+		doc.diags = err.(diag.List)
+		return doc
+	}
+	parser := parser.Parser{}
+	parser.Init(scanner.Tokens())
+	stmts, err := parser.Parse()
+	// the statements with syntax errors are nil
+	for _, stmt := range stmts {
+		if stmt != nil {
+			doc.stmts = append(doc.stmts, stmt)
+		}
+	}
+
+	resolver := resolver.Resolver{Symbols: doc.symbols}
+	resolveErr := resolver.Resolve(doc.stmts)
+	if err != nil {
+		doc.diags = err.(diag.List)
+	} else if resolveErr != nil {
+		doc.diags = resolveErr.(diag.List)
+	}
+	return doc
+}
+
+// offset returns the byte offset of a position, positions past the
+// end of a line are at its end, their character counts UTF-16 units
+func (doc *document) offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(doc.lines) {
+		return len(doc.text)
+	}
+	offset := doc.lines[pos.Line]
+	for units := 0; units < pos.Character && offset < len(doc.text); {
+		c, size := utf8.DecodeRuneInString(doc.text[offset:])
+		if c == '\n' {
+			break
+		}
+		units += len(utf16.Encode([]rune{c}))
+		offset += size
+	}
+	return offset
+}
+
+// position returns the position of a byte offset
+func (doc *document) position(offset int) Position {
+	line := sort.Search(len(doc.lines), func(i int) bool { return doc.lines[i] > offset }) - 1
+	start := doc.lines[line]
+	return Position{Line: line, Character: len(utf16.Encode([]rune(doc.text[start:offset])))}
+}
+
+func (doc *document) rangeOf(span token.Span) Range {
+	end := span.End
+	if end < span.Offset {
+		end = span.Offset
+	}
+	return Range{Start: doc.position(span.Offset), End: doc.position(end)}
+}
+
+func (doc *document) location(span token.Span) Location {
+	return Location{URI: doc.uri, Range: doc.rangeOf(span)}
+}
+
+// diagnostics converts the diagnostics of the analysis
+func (doc *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, d := range doc.diags {
+		severity := severityError
+		if d.Severity == diag.Warning {
+			severity = severityWarning
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    doc.rangeOf(token.Span{Offset: d.Offset, End: d.End}),
+			Severity: severity,
+			Code:     string(d.Code),
+			Source:   "golox",
+			Message:  d.Message,
+		})
+	}
+	return diagnostics
+}
+
+// declaration returns the declaration at a position, nil if there is none
+func (doc *document) declaration(pos Position) *resolver.Declaration {
+	return doc.symbols.At(doc.offset(pos))
+}
+
+func (doc *document) definition(pos Position) interface{} {
+	d := doc.declaration(pos)
+	if d == nil {
+		return nil
+	}
+	return doc.location(d.Span)
+}
+
+func (doc *document) references(pos Position, includeDeclaration bool) []Location {
+	locations := []Location{}
+	d := doc.declaration(pos)
+	if d == nil {
+		return locations
+	}
+	if includeDeclaration {
+		locations = append(locations, doc.location(d.Span))
+	}
+	for _, ref := range d.References {
+		locations = append(locations, doc.location(ref))
+	}
+	return locations
+}
+
+func (doc *document) hover(pos Position) interface{} {
+	offset := doc.offset(pos)
+	d := doc.symbols.At(offset)
+	if d == nil {
+		return nil
+	}
+	// the hovered name is the declaration or one of its references
+	span := d.Span
+	for _, ref := range d.References {
+		if offset >= ref.Offset && offset <= ref.End {
+			span = ref
+		}
+	}
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```lox\n" + doc.signature(d) + "\n```"},
+		Range:    doc.rangeOf(span),
+	}
+}
+
+// signature describes a declaration, with the parameters of
+// functions and methods and the superclass of classes
+func (doc *document) signature(d *resolver.Declaration) string {
+	describe := d.Kind.String() + " " + d.Name
+	var found string
+	doc.walk(func(n ast.Stmt) {
+		switch n := n.(type) {
+		case *ast.Function:
+			if n.Name.Offset == d.Span.Offset {
+				found = describe + "(" + params(n) + ")"
+			}
+		case *ast.Class:
+			if n.Name.Offset == d.Span.Offset && n.Superclass != nil {
+				found = describe + " < " + n.Superclass.Token.Lexeme
+			}
+		}
+	})
+	if found != "" {
+		return found
+	}
+	return describe
+}
+
+func params(f *ast.Function) string {
+	names := make([]string, len(f.Params))
+	for i, param := range f.Params {
+		names[i] = param.Lexeme
+	}
+	return strings.Join(names, ", ")
+}
+
+// walk calls visit with the statements of the document and the
+// ones nested in them, the methods of classes are visited as
+// functions. the functions of expressions are not visited.
+func (doc *document) walk(visit func(ast.Stmt)) {
+	var walk func(stmts ...ast.Stmt)
+	walk = func(stmts ...ast.Stmt) {
+		for _, stmt := range stmts {
+			if stmt == nil {
+				continue
+			}
+			visit(stmt)
+			switch stmt := stmt.(type) {
+			case *ast.Block:
+				walk(stmt.Content...)
+			case *ast.If:
+				walk(stmt.Then, stmt.Else)
+			case *ast.While:
+				walk(stmt.Body)
+			case *ast.Function:
+				walk(stmt.Body...)
+			case *ast.Class:
+				for _, method := range stmt.Methods {
+					walk(method)
+				}
+			case *ast.Try:
+				walk(stmt.Body)
+				if stmt.Catch != nil {
+					walk(stmt.Catch)
+				}
+				if stmt.Finally != nil {
+					walk(stmt.Finally)
+				}
+			}
+		}
+	}
+	walk(doc.stmts...)
+}
+
+// outline returns the classes, methods and functions of the document,
+// the functions declared in a function are its children
+func (doc *document) outline() []DocumentSymbol {
+	var symbols func(stmts []ast.Stmt, kind int) []DocumentSymbol
+	symbols = func(stmts []ast.Stmt, kind int) []DocumentSymbol {
+		outline := []DocumentSymbol{}
+		for _, stmt := range stmts {
+			switch stmt := stmt.(type) {
+			case *ast.Function:
+				outline = append(outline, DocumentSymbol{
+					Name:           stmt.Name.Lexeme,
+					Detail:         "(" + params(stmt) + ")",
+					Kind:           kind,
+					Range:          doc.rangeOf(stmt.Position()),
+					SelectionRange: doc.rangeOf(token.SpanOf(stmt.Name)),
+					Children:       symbols(stmt.Body, symbolFunction),
+				})
+			case *ast.Class:
+				methods := make([]ast.Stmt, len(stmt.Methods))
+				for i, method := range stmt.Methods {
+					methods[i] = method
+				}
+				outline = append(outline, DocumentSymbol{
+					Name:           stmt.Name.Lexeme,
+					Kind:           symbolClass,
+					Range:          doc.rangeOf(stmt.Position()),
+					SelectionRange: doc.rangeOf(token.SpanOf(stmt.Name)),
+					Children:       symbols(methods, symbolMethod),
+				})
+			case *ast.Block:
+				outline = append(outline, symbols(stmt.Content, kind)...)
+			case *ast.If:
+				outline = append(outline, symbols([]ast.Stmt{stmt.Then, stmt.Else}, kind)...)
+			case *ast.While:
+				outline = append(outline, symbols([]ast.Stmt{stmt.Body}, kind)...)
+			case *ast.Try:
+				for _, block := range []*ast.Block{stmt.Body, stmt.Catch, stmt.Finally} {
+					if block != nil {
+						outline = append(outline, symbols(block.Content, kind)...)
+					}
+				}
+			}
+		}
+		return outline
+	}
+	return symbols(doc.stmts, symbolFunction)
+}
+
+// completion returns the names visible at a position: the declarations
+// in scope, the built-in functions and modules and the keywords
+func (doc *document) completion(pos Position) []CompletionItem {
+	items := []CompletionItem{}
+	seen := map[string]bool{}
+	for _, d := range doc.symbols.Visible(doc.offset(pos)) {
+		items = append(items, CompletionItem{Label: d.Name, Kind: completionKind(d.Kind), Detail: d.Kind.String()})
+		seen[d.Name] = true
+	}
+	for _, native := range value.Natives {
+		if !seen[native.Name] {
+			items = append(items, CompletionItem{Label: native.Name, Kind: completionFunction, Detail: "native function"})
+		}
+	}
+	for _, namespace := range value.Namespaces {
+		if !seen[namespace.Name] {
+			items = append(items, CompletionItem{Label: namespace.Name, Kind: completionModule, Detail: "native module"})
+		}
+	}
+
+	keywords := make([]string, 0, len(token.KeyWords))
+	for keyword := range token.KeyWords {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		items = append(items, CompletionItem{Label: keyword, Kind: completionKeyword, Detail: "keyword"})
+	}
+	return items
+}
+
+func completionKind(kind resolver.Kind) int {
+	switch kind {
+	case resolver.FunctionDecl:
+		return completionFunction
+	case resolver.ClassDecl:
+		return completionClass
+	case resolver.MethodDecl:
+		return completionMethod
+	case resolver.ModuleDecl:
+		return completionModule
+	default:
+		return completionVariable
+	}
+}
+
+// rename returns the edits renaming the declaration at a position and
+// its references. methods are looked up by name at runtime, the calls
+// to a method can't be told apart from other properties.
+func (doc *document) rename(pos Position, name string) (interface{}, error) {
+	d := doc.declaration(pos)
+	if d == nil {
+		return nil, errors.New("There is no variable to rename here.")
+	}
+	if d.Kind == resolver.MethodDecl {
+		return nil, errors.New("Methods can't be renamed, their calls are resolved at runtime.")
+	}
+	// the module of an import without a name is named after its file
+	if strings.HasPrefix(doc.text[d.Span.Offset:], `"`) {
+		return nil, fmt.Errorf("Name the module with 'import %s from' to rename it.", name)
+	}
+	if !isIdentifier(name) {
+		return nil, fmt.Errorf("'%s' is not a valid name.", name)
+	}
+
+	if name != d.Name && doc.collides(d, name) {
+		return nil, fmt.Errorf("'%s' is already used where '%s' is visible.", name, d.Name)
+	}
+
+	edits := []TextEdit{{Range: doc.rangeOf(d.Span), NewText: name}}
+	for _, ref := range d.References {
+		edits = append(edits, TextEdit{Range: doc.rangeOf(ref), NewText: name})
+	}
+	return WorkspaceEdit{Changes: map[string][]TextEdit{doc.uri: edits}}, nil
+}
+
+// collides reports whether renaming d to name would change what a
+// name refers to: name is declared in a scope overlapping the scope
+// of d or is referred to where d is visible
+func (doc *document) collides(d *resolver.Declaration, name string) bool {
+	overlaps := func(a, b token.Span) bool { return a.Offset < b.End && b.Offset < a.End }
+	within := func(span token.Span) bool { return span.Offset >= d.Scope.Offset && span.Offset < d.Scope.End }
+	for _, other := range doc.symbols.Declarations {
+		if other == d || other.Name != name || other.Kind == resolver.MethodDecl {
+			continue
+		}
+		if overlaps(other.Scope, d.Scope) {
+			return true
+		}
+		for _, ref := range other.References {
+			if within(ref) {
+				return true
+			}
+		}
+	}
+	for _, ref := range doc.symbols.Free(name) {
+		if within(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIdentifier reports whether name is scanned as a single identifier
+func isIdentifier(name string) bool {
+	scanner := scanner.Scanner{}
+	scanner.Init(name)
+	if err := scanner.Scan(); err != nil {
+		return false
+	}
+	tokens := scanner.Tokens()
+	return len(tokens) == 2 && tokens[0].Type == token.IDENTIFIER && tokens[0].Lexeme == name
+}
@@ -0,0 +1,274 @@
+// Package lsp implements a Language Server Protocol server for Lox. it
+// publishes the diagnostics of the scanner, the parser and the resolver
+// and answers definition, references, hover, document symbols, completion
+// and rename requests from the declarations the resolver records.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// ErrNoShutdown is returned by Serve when the client exits or
+// closes the connection without asking the server to shut down
+var ErrNoShutdown = errors.New("lsp: exit without shutdown")
+
+// Server answers the messages of one client, its documents are
+// the ones the client opened, kept in sync with their editors
+type Server struct {
+	in  *textproto.Reader
+	out io.Writer
+
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// Serve reads the messages of a client from in and writes the
+// responses to out until the client sends the exit notification
+func Serve(in io.Reader, out io.Writer) error {
+	s := &Server{
+		in:   textproto.NewReader(bufio.NewReader(in)),
+		out:  out,
+		docs: map[string]*document{},
+	}
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return ErrNoShutdown
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads a message, which is preceded by a
+// header giving the length of its content
+func (s *Server) read() (*message, error) {
+	header, err := s.in.ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("lsp: invalid Content-Length: %q", header.Get("Content-Length"))
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(s.in.R, content); err != nil {
+		return nil, err
+	}
+	var msg message
+	if err := json.Unmarshal(content, &msg); err != nil {
+		return nil, fmt.Errorf("lsp: invalid message: %v", err)
+	}
+	return &msg, nil
+}
+
+func (s *Server) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	return err
+}
+
+// notify sends a notification to the client
+func (s *Server) notify(method string, params interface{}) error {
+	content, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.write(&message{Method: method, Params: content})
+}
+
+// handle answers a request or acts on a notification, the
+// returned error is about the connection, not the message
+func (s *Server) handle(msg *message) error {
+	result, err := s.dispatch(msg)
+	// notifications have no response
+	if msg.ID == nil {
+		return nil
+	}
+
+	response := &message{ID: msg.ID}
+	if err != nil {
+		var e *responseError
+		if !errors.As(err, &e) {
+			e = &responseError{Code: invalidParams, Message: err.Error()}
+		}
+		response.Error = e
+		return s.write(response)
+	}
+	if response.Result, err = json.Marshal(result); err != nil {
+		return err
+	}
+	return s.write(response)
+}
+
+// dispatch runs the handler of the method of msg
+func (s *Server) dispatch(msg *message) (interface{}, error) {
+	switch {
+	case msg.Method == "initialize":
+		s.initialized = true
+		return s.initialize(), nil
+	case !s.initialized:
+		return nil, &responseError{Code: invalidRequest, Message: "The server is not initialized."}
+	case s.shutdown:
+		return nil, &responseError{Code: invalidRequest, Message: "The server is shut down."}
+	}
+
+	switch msg.Method {
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// the last change holds the current text
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return nil, s.update(params.TextDocument.URI, text)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		// the diagnostics of a closed document are cleared
+		return nil, s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		doc, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return doc.definition(params.Position), nil
+	case "textDocument/references":
+		var params ReferenceParams
+		doc, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return doc.references(params.Position, params.Context.IncludeDeclaration), nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		doc, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return doc.hover(params.Position), nil
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		doc, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return doc.outline(), nil
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		doc, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return doc.completion(params.Position), nil
+	case "textDocument/rename":
+		var params RenameParams
+		doc, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return doc.rename(params.Position, params.NewName)
+	}
+	return nil, &responseError{Code: methodNotFound, Message: fmt.Sprintf("Unsupported method %s.", msg.Method)}
+}
+
+func (s *Server) initialize() interface{} {
+	type capabilities struct {
+		// TextDocumentSync is 1 for full synchronization,
+		// changes hold the whole text of the document
+		TextDocumentSync       int      `json:"textDocumentSync"`
+		DefinitionProvider     bool     `json:"definitionProvider"`
+		ReferencesProvider     bool     `json:"referencesProvider"`
+		HoverProvider          bool     `json:"hoverProvider"`
+		DocumentSymbolProvider bool     `json:"documentSymbolProvider"`
+		CompletionProvider     struct{} `json:"completionProvider"`
+		RenameProvider         bool     `json:"renameProvider"`
+	}
+	type serverInfo struct {
+		Name string `json:"name"`
+	}
+	return struct {
+		Capabilities capabilities `json:"capabilities"`
+		ServerInfo   serverInfo   `json:"serverInfo"`
+	}{
+		Capabilities: capabilities{
+			TextDocumentSync:       1,
+			DefinitionProvider:     true,
+			ReferencesProvider:     true,
+			HoverProvider:          true,
+			DocumentSymbolProvider: true,
+			RenameProvider:         true,
+		},
+		ServerInfo: serverInfo{Name: "golox"},
+	}
+}
+
+// update analyzes the new text of a document and publishes its diagnostics
+func (s *Server) update(uri, text string) error {
+	doc := analyze(uri, text)
+	s.docs[uri] = doc
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: doc.diagnostics(),
+	})
+}
+
+// document decodes the params of a request about a document
+// into v and returns the document id identifies
+func (s *Server) document(raw json.RawMessage, v interface{}, id *TextDocumentIdentifier) (*document, error) {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return nil, err
+	}
+	doc, ok := s.docs[id.URI]
+	if !ok {
+		return nil, fmt.Errorf("The document %s is not open.", id.URI)
+	}
+	return doc, nil
+}
@@ -9,9 +9,7 @@ import (
 )
 
 func main() {
-	scanner := scanner.Scanner{Error: func(line int, errMessage string) {
-		fmt.Printf("[line %d] Error: %s\n", line, errMessage)
-	}}
+	scanner := scanner.Scanner{}
 
 	src := `
 		class Cake {
@@ -27,20 +25,22 @@ func main() {
 	`
 
 	scanner.Init(src)
-	scanner.Scan()
+	if err := scanner.Scan(); err != nil {
+		fmt.Println(err)
+	}
 
 	fmt.Println(scanner.Tokens())
 
-	parser := parser.Parser{Error: func(line int, errMessage string) {
-		fmt.Printf("[line %d] Error: %s\n", line, errMessage)
-	}}
+	parser := parser.Parser{}
 	parser.Init(scanner.Tokens())
 
-	stmts := parser.Parse()
-	if parser.ErrorCount == 0 {
-		printer := printer.PrettyPrinter{}
-		for _, stmt := range stmts {
-			fmt.Println(printer.PrintStmt(stmt))
-		}
+	stmts, err := parser.Parse()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	printer := printer.PrettyPrinter{}
+	for _, stmt := range stmts {
+		fmt.Println(printer.PrintStmt(stmt))
 	}
 }
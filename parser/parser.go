@@ -2,17 +2,19 @@ package parser
 
 import (
 	"fmt"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
 	"github.com/taki-mekhalfa/golox/token"
 )
 
 type Parser struct {
 	src []token.Token
 
-	Error      func(line int, errMessage string)
-	ErrorCount int
+	diags diag.List
 
 	current int
 
@@ -23,12 +25,24 @@ func (p *Parser) Init(src []token.Token) {
 	p.src = src
 }
 
-func (p *Parser) Parse() []ast.Stmt {
+// Parse parses the tokens into statements, the returned
+// error is a diag.List of the parsing errors.
+func (p *Parser) Parse() ([]ast.Stmt, error) {
 	for !p.isAtEnd() {
 		stmt, _ := p.declaration()
 		p.stmts = append(p.stmts, stmt)
 	}
-	return p.stmts
+	return p.stmts, p.diags.Err()
+}
+
+// ParseExpr parses the tokens into a single expression, the
+// returned error is a diag.List of the parsing errors.
+func (p *Parser) ParseExpr() (ast.Expr, error) {
+	expr, err := p.expression()
+	if err == nil && !p.isAtEnd() {
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected the end of the expression.")
+	}
+	return expr, p.diags.Err()
 }
 
 func (p *Parser) declaration() (ast.Stmt, error) {
@@ -38,10 +52,14 @@ func (p *Parser) declaration() (ast.Stmt, error) {
 	switch {
 	case p.match(token.VAR):
 		stmt, err = p.var_()
-	case p.match(token.FUN):
+	// 'fun' followed by a '(' starts an anonymous function expression
+	case p.peek().Type == token.FUN && p.peekNext().Type != token.LEFT_PAREN:
+		p.next()
 		stmt, err = p.function()
 	case p.match(token.CLASS):
 		stmt, err = p.class()
+	case p.peek().Type == token.IMPORT:
+		stmt, err = p.import_()
 	default:
 		stmt, err = p.statement()
 	}
@@ -59,7 +77,7 @@ func (p *Parser) synchronize() {
 		case token.SEMICOLON:
 			p.next()
 			return
-		case token.CLASS, token.FUN, token.VAR, token.FOR, token.IF, token.WHILE, token.PRINT, token.RETURN, token.LEFT_BRACE:
+		case token.CLASS, token.FUN, token.VAR, token.IMPORT, token.FOR, token.IF, token.WHILE, token.PRINT, token.RETURN, token.BREAK, token.CONTINUE, token.TRY, token.THROW, token.LEFT_BRACE:
 			return
 		default:
 			p.next()
@@ -68,8 +86,9 @@ func (p *Parser) synchronize() {
 }
 
 func (p *Parser) var_() (ast.Stmt, error) {
+	start := p.previous()
 	if p.peek().Type != token.IDENTIFIER {
-		p.reportError(p.peek().Line, "Expected identifier after var.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected identifier after var.")
 		return nil, fmt.Errorf("line %d: expected identifier after var", p.peek().Line)
 	}
 
@@ -84,21 +103,73 @@ func (p *Parser) var_() (ast.Stmt, error) {
 	}
 
 	if !p.match(token.SEMICOLON) {
-		p.reportError(p.peek().Line, "Expected ; after variable declaration.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ; after variable declaration.")
 		return nil, fmt.Errorf("line %d: expected ; after variable declaration", p.peek().Line)
 	}
 
-	return &ast.VarStmt{Name: varToken.Lexeme, Initializer: initializer, Token: varToken}, nil
+	return &ast.VarStmt{Pos: p.pos(token.SpanOf(start)), Name: varToken.Lexeme, Initializer: initializer, Token: varToken}, nil
+}
+
+// parse an import "path/to/module.lox"; binding the module to its file's
+// base name or an import name from "path/to/module.lox"; binding it to name
+func (p *Parser) import_() (ast.Stmt, error) {
+	import_ := &ast.Import{Keyword: p.next()}
+
+	if p.peek().Type == token.IDENTIFIER {
+		import_.Name = p.next()
+		if p.peek().Type != token.IDENTIFIER || p.peek().Lexeme != "from" {
+			p.reportError(p.peek(), diag.ExpectedToken, "Expected 'from' after module name.")
+			return nil, fmt.Errorf("line %d: expected 'from' after module name", p.peek().Line)
+		}
+		p.next()
+	}
+
+	if p.peek().Type != token.STRING {
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected module path.")
+		return nil, fmt.Errorf("line %d: expected module path", p.peek().Line)
+	}
+	path := p.next()
+	import_.Path = path.Literal
+
+	if import_.Name.Lexeme == "" {
+		name := strings.TrimSuffix(filepath.Base(import_.Path), filepath.Ext(import_.Path))
+		if !isIdentifier(name) {
+			p.reportError(path, diag.InvalidModuleName, "Can't name a module after its path, use import name from \"path\".")
+			return nil, fmt.Errorf("line %d: can't name a module after its path", path.Line)
+		}
+		// the name stands at the path in the source
+		import_.Name = path
+		import_.Name.Type, import_.Name.Lexeme = token.IDENTIFIER, name
+	}
+
+	if !p.match(token.SEMICOLON) {
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ; after import.")
+		return nil, fmt.Errorf("line %d: expected ; after import", p.peek().Line)
+	}
+	import_.Pos = p.pos(token.SpanOf(import_.Keyword))
+	return import_, nil
 }
 
 func (p *Parser) class() (ast.Stmt, error) {
+	start := p.previous()
 	if p.peek().Type != token.IDENTIFIER {
-		p.reportError(p.peek().Line, "Expected class name.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected class name.")
 		return nil, fmt.Errorf("line %d: expected class name", p.peek().Line)
 	}
 	class := p.next()
+
+	var superclass *ast.Var
+	if p.match(token.LESS) {
+		if p.peek().Type != token.IDENTIFIER {
+			p.reportError(p.peek(), diag.ExpectedToken, "Expected superclass name.")
+			return nil, fmt.Errorf("line %d: expected superclass name", p.peek().Line)
+		}
+		name := p.next()
+		superclass = &ast.Var{Pos: p.pos(token.SpanOf(name)), Token: name}
+	}
+
 	if !p.match(token.LEFT_BRACE) {
-		p.reportError(p.peek().Line, "Expected { after class name.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected { after class name.")
 		return nil, fmt.Errorf("line %d: expected { after class name", p.peek().Line)
 	}
 	var methods []*ast.Function
@@ -110,30 +181,40 @@ func (p *Parser) class() (ast.Stmt, error) {
 		methods = append(methods, method)
 	}
 	if !p.match(token.RIGHT_BRACE) {
-		p.reportError(p.peek().Line, "Expected } after class body.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected } after class body.")
 		return nil, fmt.Errorf("line %d: expected } after class body", p.peek().Line)
 	}
-	return &ast.Class{Name: class, Methods: methods}, nil
+	return &ast.Class{Pos: p.pos(token.SpanOf(start)), Name: class, Superclass: superclass, Methods: methods}, nil
 }
 
 func (p *Parser) function() (*ast.Function, error) {
 	if p.peek().Type != token.IDENTIFIER {
-		p.reportError(p.peek().Line, "Expected function name.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected function name.")
 		return nil, fmt.Errorf("line %d: expected function name", p.peek().Line)
 	}
 
-	functionName := p.next()
+	// declarations start at their 'fun' keyword, methods at their name
+	start := p.previous()
+	if start.Type != token.FUN {
+		start = p.peek()
+	}
+	return p.functionRest(start, p.next())
+}
+
+// functionRest parses the parameters and the body of a function
+// whose name (or 'fun' keyword for lambdas) was already consumed
+func (p *Parser) functionRest(start, functionName token.Token) (*ast.Function, error) {
 	var params []token.Token
 
 	if !p.match(token.LEFT_PAREN) {
-		p.reportError(p.peek().Line, "Expected ( after function name.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ( after function name.")
 		return nil, fmt.Errorf("line %d: expected ( after function name", p.peek().Line)
 	}
 
 	if p.peek().Type != token.RIGHT_PAREN {
 		for {
 			if p.peek().Type != token.IDENTIFIER {
-				p.reportError(p.peek().Line, "Expected parameter name.")
+				p.reportError(p.peek(), diag.ExpectedToken, "Expected parameter name.")
 				return nil, fmt.Errorf("line %d: expected parameter name", p.peek().Line)
 			}
 			params = append(params, p.next())
@@ -144,11 +225,11 @@ func (p *Parser) function() (*ast.Function, error) {
 	}
 
 	if !p.match(token.RIGHT_PAREN) {
-		p.reportError(p.peek().Line, "Expected ) after function parameters.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ) after function parameters.")
 		return nil, fmt.Errorf("line %d: expected ) after function parameters", p.peek().Line)
 	}
 	if !p.match(token.LEFT_BRACE) {
-		p.reportError(p.peek().Line, "Expected { before function body.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected { before function body.")
 		return nil, fmt.Errorf("line %d: expected { before function body", p.peek().Line)
 	}
 
@@ -156,7 +237,7 @@ func (p *Parser) function() (*ast.Function, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ast.Function{Name: functionName, Params: params, Body: block.(*ast.Block).Content}, nil
+	return &ast.Function{Pos: p.pos(token.SpanOf(start)), Name: functionName, Params: params, Body: block.(*ast.Block).Content}, nil
 }
 
 func (p *Parser) statement() (ast.Stmt, error) {
@@ -179,6 +260,28 @@ func (p *Parser) statement() (ast.Stmt, error) {
 		retToken := p.next()
 		return p.return_(retToken)
 	}
+	if p.peek().Type == token.THROW {
+		return p.throw()
+	}
+	if p.match(token.TRY) {
+		return p.try()
+	}
+	if p.peek().Type == token.BREAK {
+		keyword := p.next()
+		if !p.match(token.SEMICOLON) {
+			p.reportError(p.peek(), diag.ExpectedToken, "Expected ; after break.")
+			return nil, fmt.Errorf("line %d: expected ; after break", p.peek().Line)
+		}
+		return &ast.Break{Pos: p.pos(token.SpanOf(keyword)), Keyword: keyword}, nil
+	}
+	if p.peek().Type == token.CONTINUE {
+		keyword := p.next()
+		if !p.match(token.SEMICOLON) {
+			p.reportError(p.peek(), diag.ExpectedToken, "Expected ; after continue.")
+			return nil, fmt.Errorf("line %d: expected ; after continue", p.peek().Line)
+		}
+		return &ast.Continue{Pos: p.pos(token.SpanOf(keyword)), Keyword: keyword}, nil
+	}
 
 	return p.expressionStmt()
 }
@@ -193,16 +296,87 @@ func (p *Parser) return_(retToken token.Token) (ast.Stmt, error) {
 		ret.Value = expr
 	}
 	if !p.match(token.SEMICOLON) {
-		p.reportError(p.peek().Line, "Expected ; after return.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ; after return.")
 		return nil, fmt.Errorf("line %d: expected ; after return", p.peek().Line)
 	}
+	ret.Pos = p.pos(token.SpanOf(retToken))
 	return ret, nil
 }
 
-// parse a for (A; B; C) {D} into an { A; while(B) {D;C} }
+func (p *Parser) throw() (ast.Stmt, error) {
+	keyword := p.next()
+	value, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.match(token.SEMICOLON) {
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ; after thrown value.")
+		return nil, fmt.Errorf("line %d: expected ; after thrown value", p.peek().Line)
+	}
+	return &ast.Throw{Pos: p.pos(token.SpanOf(keyword)), Keyword: keyword, Value: value}, nil
+}
+
+// parse a try {A} catch (e) {B} finally {C} where
+// either the catch or the finally clause can be omitted
+func (p *Parser) try() (ast.Stmt, error) {
+	try := &ast.Try{}
+	start := p.previous()
+	var err error
+	if try.Body, err = p.clause("try"); err != nil {
+		return nil, err
+	}
+
+	if p.match(token.CATCH) {
+		if !p.match(token.LEFT_PAREN) {
+			p.reportError(p.peek(), diag.ExpectedToken, "Expected ( after catch.")
+			return nil, fmt.Errorf("line %d: expected ( after catch", p.peek().Line)
+		}
+		if p.peek().Type != token.IDENTIFIER {
+			p.reportError(p.peek(), diag.ExpectedToken, "Expected catch variable name.")
+			return nil, fmt.Errorf("line %d: expected catch variable name", p.peek().Line)
+		}
+		try.CatchParam = p.next()
+		if !p.match(token.RIGHT_PAREN) {
+			p.reportError(p.peek(), diag.ExpectedToken, "Expected ) after catch variable.")
+			return nil, fmt.Errorf("line %d: expected ) after catch variable", p.peek().Line)
+		}
+		if try.Catch, err = p.clause("catch"); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.match(token.FINALLY) {
+		if try.Finally, err = p.clause("finally"); err != nil {
+			return nil, err
+		}
+	}
+
+	if try.Catch == nil && try.Finally == nil {
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected catch or finally after try block.")
+		return nil, fmt.Errorf("line %d: expected catch or finally after try block", p.peek().Line)
+	}
+	try.Pos = p.pos(token.SpanOf(start))
+	return try, nil
+}
+
+// clause parses the block following a try, catch or finally keyword
+func (p *Parser) clause(keyword string) (*ast.Block, error) {
+	if !p.match(token.LEFT_BRACE) {
+		p.reportError(p.peek(), diag.ExpectedToken, fmt.Sprintf("Expected { after %s.", keyword))
+		return nil, fmt.Errorf("line %d: expected { after %s", p.peek().Line, keyword)
+	}
+	block, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	return block.(*ast.Block), nil
+}
+
+// parse a for (A; B; C) {D} into an { A; while(B) {D} } with C as the loop increment
 func (p *Parser) for_() (ast.Stmt, error) {
+	start := p.previous()
 	if !p.match(token.LEFT_PAREN) {
-		p.reportError(p.peek().Line, "Expected ( after for.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ( after for.")
 		return nil, fmt.Errorf("line %d: expected ( after for", p.peek().Line)
 	}
 
@@ -230,7 +404,7 @@ func (p *Parser) for_() (ast.Stmt, error) {
 		}
 	}
 	if !p.match(token.SEMICOLON) {
-		p.reportError(p.peek().Line, "Expected ; after for condition.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ; after for condition.")
 		return nil, fmt.Errorf("line %d: expected ; after for condition", p.peek().Line)
 	}
 
@@ -242,7 +416,7 @@ func (p *Parser) for_() (ast.Stmt, error) {
 		}
 	}
 	if !p.match(token.RIGHT_PAREN) {
-		p.reportError(p.peek().Line, "Expected ) after for clauses.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ) after for clauses.")
 		return nil, fmt.Errorf("line %d: expected ) after for clauses", p.peek().Line)
 	}
 
@@ -250,26 +424,29 @@ func (p *Parser) for_() (ast.Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	if increment != nil {
-		body = &ast.Block{Content: []ast.Stmt{body, &ast.ExprStmt{Expr: increment}}}
-	}
 
+	// the desugared nodes span the whole for statement
+	pos := p.pos(token.SpanOf(start))
+	clauses := &ast.ForClauses{Initializer: initializer, Condition: condition}
 	if condition == nil {
-		condition = &ast.Literal{Value: true}
+		condition = &ast.Literal{Pos: pos, Value: true}
 	}
 
-	body = &ast.While{Condition: condition, Body: body}
+	// the increment is not appended to the body
+	// as a 'continue' would skip it
+	body = &ast.While{Pos: pos, Condition: condition, Body: body, Increment: increment, For: clauses}
 
 	if initializer != nil {
-		body = &ast.Block{Content: []ast.Stmt{initializer, body}}
+		body = &ast.Block{Pos: pos, Content: []ast.Stmt{initializer, body}}
 	}
 
 	return body, nil
 }
 
 func (p *Parser) while() (ast.Stmt, error) {
+	start := p.previous()
 	if !p.match(token.LEFT_PAREN) {
-		p.reportError(p.peek().Line, "Expected ( after while.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ( after while.")
 		return nil, fmt.Errorf("line %d: expected ( after while", p.peek().Line)
 	}
 	condition, err := p.expression()
@@ -277,19 +454,20 @@ func (p *Parser) while() (ast.Stmt, error) {
 		return nil, err
 	}
 	if !p.match(token.RIGHT_PAREN) {
-		p.reportError(p.peek().Line, "Expected ) after while condition.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ) after while condition.")
 		return nil, fmt.Errorf("line %d: expected ) after while condition", p.peek().Line)
 	}
 	body, err := p.statement()
 	if err != nil {
 		return nil, err
 	}
-	return &ast.While{Condition: condition, Body: body}, nil
+	return &ast.While{Pos: p.pos(token.SpanOf(start)), Condition: condition, Body: body}, nil
 }
 
 func (p *Parser) if_() (ast.Stmt, error) {
+	start := p.previous()
 	if !p.match(token.LEFT_PAREN) {
-		p.reportError(p.peek().Line, "Expected ( after if.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ( after if.")
 		return nil, fmt.Errorf("line %d: expected ( after if", p.peek().Line)
 	}
 	condition, err := p.expression()
@@ -297,7 +475,7 @@ func (p *Parser) if_() (ast.Stmt, error) {
 		return nil, err
 	}
 	if !p.match(token.RIGHT_PAREN) {
-		p.reportError(p.peek().Line, "Expected ) after if condition.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ) after if condition.")
 		return nil, fmt.Errorf("line %d: expected ) after if condition", p.peek().Line)
 	}
 	then, err := p.statement()
@@ -311,10 +489,12 @@ func (p *Parser) if_() (ast.Stmt, error) {
 			return nil, err
 		}
 	}
-	return &ast.If{Condition: condition, Then: then, Else: else_}, nil
+	return &ast.If{Pos: p.pos(token.SpanOf(start)), Condition: condition, Then: then, Else: else_}, nil
 }
 
+// block parses the statements following a '{' up to the closing '}'
 func (p *Parser) block() (ast.Stmt, error) {
+	start := p.previous()
 	var content []ast.Stmt
 	for !p.isAtEnd() && p.peek().Type != token.RIGHT_BRACE {
 		stmt, err := p.declaration()
@@ -324,26 +504,27 @@ func (p *Parser) block() (ast.Stmt, error) {
 		content = append(content, stmt)
 	}
 	if p.peek().Type != token.RIGHT_BRACE {
-		p.reportError(p.peek().Line, "Expected } after block.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected } after block.")
 		return nil, fmt.Errorf("line %d: expected } after block", p.peek().Line)
 	}
 
 	// consume the '}'
 	p.next()
-	return &ast.Block{Content: content}, nil
+	return &ast.Block{Pos: p.pos(token.SpanOf(start)), Content: content}, nil
 }
 
 func (p *Parser) printStmt() (ast.Stmt, error) {
+	start := p.previous()
 	expr, err := p.expression()
 	if err != nil {
 		return nil, err
 	}
 	if p.peek().Type != token.SEMICOLON {
-		p.reportError(p.peek().Line, "Expected ; after expression.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ; after expression.")
 		return nil, fmt.Errorf("line %d: expected ; after expression", p.peek().Line)
 	}
 	p.next()
-	return &ast.Print{Expr: expr}, nil
+	return &ast.Print{Pos: p.pos(token.SpanOf(start)), Expr: expr}, nil
 }
 
 func (p *Parser) expressionStmt() (ast.Stmt, error) {
@@ -352,11 +533,11 @@ func (p *Parser) expressionStmt() (ast.Stmt, error) {
 		return nil, err
 	}
 	if p.peek().Type != token.SEMICOLON {
-		p.reportError(p.peek().Line, "Expected ; after expression.")
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ; after expression.")
 		return nil, fmt.Errorf("line %d: expected ; after expression", p.peek().Line)
 	}
 	p.next()
-	return &ast.ExprStmt{Expr: expr}, nil
+	return &ast.ExprStmt{Pos: p.pos(expr.Position()), Expr: expr}, nil
 }
 
 func (p *Parser) expression() (ast.Expr, error) {
@@ -374,12 +555,15 @@ func (p *Parser) assignment() (ast.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
+		pos := p.pos(expr.Position())
 		if var_, ok := expr.(*ast.Var); ok {
-			return &ast.Assign{Identifier: var_.Token, Value: value}, nil
+			return &ast.Assign{Pos: pos, Identifier: var_.Token, Value: value}, nil
 		} else if get, ok := expr.(*ast.Get); ok {
-			return &ast.Set{Object: get.Object, Property: get.Property, Value: value}, nil
+			return &ast.Set{Pos: pos, Object: get.Object, Property: get.Property, Value: value}, nil
+		} else if index, ok := expr.(*ast.Index); ok {
+			return &ast.SetIndex{Pos: pos, Object: index.Object, Bracket: index.Bracket, Index: index.Index, Value: value}, nil
 		} else {
-			p.reportError(equal.Line, "Invalid assignment target.")
+			p.reportError(equal, diag.InvalidAssignmentTarget, "Invalid assignment target.")
 		}
 	}
 	return expr, nil
@@ -399,7 +583,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
-			left = &ast.Logical{Left: left, Operator: op, Right: right}
+			left = &ast.Logical{Pos: p.pos(left.Position()), Left: left, Operator: op, Right: right}
 		default:
 			break LOOP
 		}
@@ -422,7 +606,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
-			left = &ast.Logical{Left: left, Operator: op, Right: right}
+			left = &ast.Logical{Pos: p.pos(left.Position()), Left: left, Operator: op, Right: right}
 		default:
 			break LOOP
 		}
@@ -445,7 +629,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
-			left = &ast.Binary{Left: left, Operator: op, Right: right}
+			left = &ast.Binary{Pos: p.pos(left.Position()), Left: left, Operator: op, Right: right}
 		default:
 			break LOOP
 		}
@@ -467,7 +651,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
-			left = &ast.Binary{Left: left, Operator: op, Right: right}
+			left = &ast.Binary{Pos: p.pos(left.Position()), Left: left, Operator: op, Right: right}
 		default:
 			break LOOP
 		}
@@ -489,7 +673,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
-			left = &ast.Binary{Left: left, Operator: op, Right: right}
+			left = &ast.Binary{Pos: p.pos(left.Position()), Left: left, Operator: op, Right: right}
 		default:
 			break LOOP
 		}
@@ -511,7 +695,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
-			left = &ast.Binary{Left: left, Operator: op, Right: right}
+			left = &ast.Binary{Pos: p.pos(left.Position()), Left: left, Operator: op, Right: right}
 		default:
 			break LOOP
 		}
@@ -527,7 +711,7 @@ func (p *Parser) unary() (ast.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &ast.Unary{Operator: op, Expr: unary}, nil
+		return &ast.Unary{Pos: p.pos(token.SpanOf(op)), Operator: op, Expr: unary}, nil
 	default:
 		return p.call()
 	}
@@ -542,10 +726,17 @@ func (p *Parser) call() (ast.Expr, error) {
 	for {
 		if p.match(token.DOT) {
 			if p.peek().Type != token.IDENTIFIER {
-				p.reportError(p.peek().Line, "Expected property name after '.' .")
+				p.reportError(p.peek(), diag.ExpectedToken, "Expected property name after '.' .")
 				return nil, fmt.Errorf("line %d: expected property name after '.' .", p.peek().Line)
 			}
-			expr = &ast.Get{Object: expr, Property: p.next()}
+			property := p.next()
+			expr = &ast.Get{Pos: p.pos(expr.Position()), Object: expr, Property: property}
+			continue
+		}
+		if p.match(token.LEFT_BRACKET) {
+			if expr, err = p.subscript(expr); err != nil {
+				return nil, err
+			}
 			continue
 		}
 		// if we don't encounter a '(', we should break
@@ -554,7 +745,8 @@ func (p *Parser) call() (ast.Expr, error) {
 		}
 		// in case the function call has not arguments
 		if p.peek().Type == token.RIGHT_PAREN {
-			expr = &ast.Call{Callee: expr, ClosingParent: p.next(), Args: nil}
+			paren := p.next()
+			expr = &ast.Call{Pos: p.pos(expr.Position()), Callee: expr, ClosingParent: paren, Args: nil}
 			continue
 		}
 
@@ -564,14 +756,49 @@ func (p *Parser) call() (ast.Expr, error) {
 			return nil, err
 		}
 		if p.peek().Type != token.RIGHT_PAREN {
-			p.reportError(p.peek().Line, "Expected ) after function call.")
+			p.reportError(p.peek(), diag.ExpectedToken, "Expected ) after function call.")
 			return nil, fmt.Errorf("line %d: expected ) after function call", p.peek().Line)
 		}
-		expr = &ast.Call{Callee: expr, ClosingParent: p.next(), Args: args}
+		paren := p.next()
+		expr = &ast.Call{Pos: p.pos(expr.Position()), Callee: expr, ClosingParent: paren, Args: args}
 	}
 	return expr, nil
 }
 
+// subscript parses what follows a '[' after an expression,
+// either an index xs[i] or a slice xs[start:end] where both bounds are optional
+func (p *Parser) subscript(object ast.Expr) (ast.Expr, error) {
+	var start ast.Expr
+	var err error
+	if p.peek().Type != token.COLON {
+		if start, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !p.match(token.COLON) {
+		if p.peek().Type != token.RIGHT_BRACKET {
+			p.reportError(p.peek(), diag.ExpectedToken, "Expected ] after index.")
+			return nil, fmt.Errorf("line %d: expected ] after index", p.peek().Line)
+		}
+		bracket := p.next()
+		return &ast.Index{Pos: p.pos(object.Position()), Object: object, Bracket: bracket, Index: start}, nil
+	}
+
+	var end ast.Expr
+	if p.peek().Type != token.RIGHT_BRACKET {
+		if end, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek().Type != token.RIGHT_BRACKET {
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ] after slice.")
+		return nil, fmt.Errorf("line %d: expected ] after slice", p.peek().Line)
+	}
+	bracket := p.next()
+	return &ast.Slice{Pos: p.pos(object.Position()), Object: object, Bracket: bracket, Start: start, End: end}, nil
+}
+
 func (p *Parser) args() ([]ast.Expr, error) {
 	var args []ast.Expr
 	for {
@@ -588,30 +815,59 @@ func (p *Parser) args() ([]ast.Expr, error) {
 }
 
 func (p *Parser) primary() (ast.Expr, error) {
+	start := p.peek()
 	if p.match(token.FALSE) {
-		return &ast.Literal{Value: false}, nil
+		return &ast.Literal{Pos: p.pos(token.SpanOf(start)), Value: false}, nil
 	}
 	if p.match(token.TRUE) {
-		return &ast.Literal{Value: true}, nil
+		return &ast.Literal{Pos: p.pos(token.SpanOf(start)), Value: true}, nil
 	}
-	if p.peek().Type == token.THIS {
-		return &ast.This{Keyword: p.next()}, nil
+	if p.match(token.THIS) {
+		return &ast.This{Pos: p.pos(token.SpanOf(start)), Keyword: start}, nil
 	}
-	if p.peek().Type == token.IDENTIFIER {
-		return &ast.Var{Token: p.next()}, nil
+	if p.peek().Type == token.SUPER {
+		keyword := p.next()
+		if !p.match(token.DOT) {
+			p.reportError(p.peek(), diag.ExpectedToken, "Expected '.' after 'super'.")
+			return nil, fmt.Errorf("line %d: expected '.' after 'super'", p.peek().Line)
+		}
+		if p.peek().Type != token.IDENTIFIER {
+			p.reportError(p.peek(), diag.ExpectedToken, "Expected superclass method name.")
+			return nil, fmt.Errorf("line %d: expected superclass method name", p.peek().Line)
+		}
+		method := p.next()
+		return &ast.Super{Pos: p.pos(token.SpanOf(keyword)), Keyword: keyword, Method: method}, nil
+	}
+	if p.match(token.IDENTIFIER) {
+		return &ast.Var{Pos: p.pos(token.SpanOf(start)), Token: start}, nil
 	}
 	if p.match(token.NIL) {
-		return &ast.Literal{Value: nil}, nil
+		return &ast.Literal{Pos: p.pos(token.SpanOf(start)), Value: nil}, nil
 	}
-	if p.peek().Type == token.STRING {
-		lexeme := p.next().Lexeme
-		return &ast.Literal{Value: lexeme[1 : len(lexeme)-1]}, nil
+	if p.match(token.STRING) {
+		return &ast.Literal{Pos: p.pos(token.SpanOf(start)), Value: start.Literal}, nil
 	}
-	if p.peek().Type == token.NUMBER {
-		lexeme := p.next().Lexeme
+	if p.match(token.NUMBER) {
 		// ignore error as this is guaranteed to be a valid float after scanning
-		number, _ := strconv.ParseFloat(lexeme, 64)
-		return &ast.Literal{Value: number}, nil
+		number, _ := strconv.ParseFloat(start.Lexeme, 64)
+		return &ast.Literal{Pos: p.pos(token.SpanOf(start)), Value: number}, nil
+	}
+
+	if p.peek().Type == token.FUN {
+		keyword := p.next()
+		function, err := p.functionRest(keyword, keyword)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Lambda{Pos: function.Pos, Function: function}, nil
+	}
+	if p.peek().Type == token.LEFT_BRACKET {
+		return p.list()
+	}
+	// a '{' in an expression position always starts a map,
+	// a '{' starting a statement is a block.
+	if p.peek().Type == token.LEFT_BRACE {
+		return p.map_()
 	}
 
 	// This should be a left paren
@@ -621,13 +877,59 @@ func (p *Parser) primary() (ast.Expr, error) {
 			return nil, err
 		}
 		if p.peek().Type != token.RIGHT_PAREN {
-			p.reportError(p.peek().Line, "Expected ) after expression.")
+			p.reportError(p.peek(), diag.ExpectedToken, "Expected ) after expression.")
 			return nil, fmt.Errorf("line %d: expected ) after expression", p.peek().Line)
 		}
 		p.next()
-		return &ast.Grouping{Expr: expr}, nil
+		return &ast.Grouping{Pos: p.pos(token.SpanOf(start)), Expr: expr}, nil
 	}
 
-	p.reportError(p.peek().Line, "Expected an expression.")
+	p.reportError(p.peek(), diag.ExpectedExpression, "Expected an expression.")
 	return nil, fmt.Errorf("line %d: expected an expression", p.peek().Line)
 }
+
+func (p *Parser) list() (ast.Expr, error) {
+	bracket := p.next()
+	var elements []ast.Expr
+	if p.peek().Type != token.RIGHT_BRACKET {
+		var err error
+		if elements, err = p.args(); err != nil {
+			return nil, err
+		}
+	}
+	if !p.match(token.RIGHT_BRACKET) {
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected ] after list elements.")
+		return nil, fmt.Errorf("line %d: expected ] after list elements", p.peek().Line)
+	}
+	return &ast.List{Pos: p.pos(token.SpanOf(bracket)), Bracket: bracket, Elements: elements}, nil
+}
+
+func (p *Parser) map_() (ast.Expr, error) {
+	brace := p.next()
+	var keys, values []ast.Expr
+	if p.peek().Type != token.RIGHT_BRACE {
+		for {
+			key, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if !p.match(token.COLON) {
+				p.reportError(p.peek(), diag.ExpectedToken, "Expected : after map key.")
+				return nil, fmt.Errorf("line %d: expected : after map key", p.peek().Line)
+			}
+			value, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			keys, values = append(keys, key), append(values, value)
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+	}
+	if !p.match(token.RIGHT_BRACE) {
+		p.reportError(p.peek(), diag.ExpectedToken, "Expected } after map entries.")
+		return nil, fmt.Errorf("line %d: expected } after map entries", p.peek().Line)
+	}
+	return &ast.Map{Pos: p.pos(token.SpanOf(brace)), Brace: brace, Keys: keys, Values: values}, nil
+}
@@ -1,6 +1,10 @@
 package parser
 
-import "github.com/taki-mekhalfa/golox/token"
+import (
+	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/token"
+)
 
 func (p *Parser) isAtEnd() bool {
 	return p.peek().Type == token.EOF
@@ -10,6 +14,24 @@ func (p *Parser) peek() token.Token {
 	return p.src[p.current]
 }
 
+func (p *Parser) peekNext() token.Token {
+	if p.isAtEnd() {
+		return p.peek()
+	}
+	return p.src[p.current+1]
+}
+
+// previous returns the last consumed token
+func (p *Parser) previous() token.Token {
+	return p.src[p.current-1]
+}
+
+// pos returns the position of a node starting at
+// start and ending with the last consumed token
+func (p *Parser) pos(start token.Span) ast.Pos {
+	return ast.Pos{Span: start.To(token.SpanOf(p.previous()))}
+}
+
 func (p *Parser) next() token.Token {
 	p.current++
 	return p.src[p.current-1]
@@ -28,7 +50,21 @@ func (p *Parser) match(typ token.Type) bool {
 	return false
 }
 
-func (p *Parser) reportError(line int, errMessage string) {
-	p.ErrorCount++
-	p.Error(line, errMessage)
+// isIdentifier reports whether name is a valid identifier and not a keyword
+func isIdentifier(name string) bool {
+	if _, ok := token.KeyWords[name]; ok || name == "" {
+		return false
+	}
+	for i, c := range name {
+		isAlpha := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+		if !isAlpha && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// reportError reports an error at the given token
+func (p *Parser) reportError(t token.Token, code diag.Code, errMessage string) {
+	p.diags = append(p.diags, diag.New(diag.Parse, token.SpanOf(t), code, errMessage))
 }
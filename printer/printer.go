@@ -73,6 +73,61 @@ func (p PrettyPrinter) VisitThis(this *This) interface{} {
 	return "this"
 }
 
+func (p PrettyPrinter) VisitSuper(s *Super) interface{} {
+	return "super." + s.Method.Lexeme
+}
+
+func (p PrettyPrinter) VisitList(l *List) interface{} {
+	var builder strings.Builder
+	builder.WriteString("[")
+	for i, element := range l.Elements {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(p.PrintExpr(element))
+	}
+	builder.WriteString("]")
+	return builder.String()
+}
+
+func (p PrettyPrinter) VisitMap(m *Map) interface{} {
+	var builder strings.Builder
+	builder.WriteString("{")
+	for i := range m.Keys {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(p.PrintExpr(m.Keys[i]))
+		builder.WriteString(": ")
+		builder.WriteString(p.PrintExpr(m.Values[i]))
+	}
+	builder.WriteString("}")
+	return builder.String()
+}
+
+func (p PrettyPrinter) VisitIndex(i *Index) interface{} {
+	return p.PrintExpr(i.Object) + "[" + p.PrintExpr(i.Index) + "]"
+}
+
+func (p PrettyPrinter) VisitSlice(s *Slice) interface{} {
+	var builder strings.Builder
+	builder.WriteString(p.PrintExpr(s.Object))
+	builder.WriteString("[")
+	if s.Start != nil {
+		builder.WriteString(p.PrintExpr(s.Start))
+	}
+	builder.WriteString(":")
+	if s.End != nil {
+		builder.WriteString(p.PrintExpr(s.End))
+	}
+	builder.WriteString("]")
+	return builder.String()
+}
+
+func (p PrettyPrinter) VisitSetIndex(s *SetIndex) interface{} {
+	return p.PrintExpr(s.Object) + "[" + p.PrintExpr(s.Index) + "] = " + p.PrintExpr(s.Value)
+}
+
 func (p PrettyPrinter) VisitPrint(printStmt *Print) interface{} {
 	return fmt.Sprint("PRINT ", p.PrintExpr(printStmt.Expr))
 }
@@ -122,15 +177,59 @@ func (p PrettyPrinter) VisitWhile(while *While) interface{} {
 	var builder strings.Builder
 	builder.WriteString("while (")
 	builder.WriteString(p.PrintExpr(while.Condition))
+	if while.Increment != nil {
+		builder.WriteString("; ")
+		builder.WriteString(p.PrintExpr(while.Increment))
+	}
 	builder.WriteString(") ")
 	builder.WriteString(p.PrintStmt(while.Body))
 	return builder.String()
 }
 
+func (p PrettyPrinter) VisitThrow(t *Throw) interface{} {
+	return "throw " + p.PrintExpr(t.Value)
+}
+
+func (p PrettyPrinter) VisitTry(t *Try) interface{} {
+	var builder strings.Builder
+	builder.WriteString("try ")
+	builder.WriteString(p.PrintStmt(t.Body))
+	if t.Catch != nil {
+		builder.WriteString(" catch (")
+		builder.WriteString(t.CatchParam.Lexeme)
+		builder.WriteString(") ")
+		builder.WriteString(p.PrintStmt(t.Catch))
+	}
+	if t.Finally != nil {
+		builder.WriteString(" finally ")
+		builder.WriteString(p.PrintStmt(t.Finally))
+	}
+	return builder.String()
+}
+
+func (p PrettyPrinter) VisitImport(i *Import) interface{} {
+	return fmt.Sprintf("import %s from %q", i.Name.Lexeme, i.Path)
+}
+
+func (p PrettyPrinter) VisitBreak(b *Break) interface{} {
+	return "break"
+}
+
+func (p PrettyPrinter) VisitContinue(c *Continue) interface{} {
+	return "continue"
+}
+
 func (p PrettyPrinter) VisitFunction(f *Function) interface{} {
+	return "fun " + f.Name.Lexeme + p.function(f)
+}
+
+func (p PrettyPrinter) VisitLambda(l *Lambda) interface{} {
+	return "fun " + p.function(l.Function)
+}
+
+// function prints the parameters and the body of a function
+func (p PrettyPrinter) function(f *Function) string {
 	var builder strings.Builder
-	builder.WriteString("fun ")
-	builder.WriteString(f.Name.Lexeme)
 	builder.WriteString("(")
 	for _, param := range f.Params {
 		builder.WriteString(param.Lexeme)
@@ -149,6 +248,10 @@ func (p PrettyPrinter) VisitClass(c *Class) interface{} {
 	var builder strings.Builder
 	builder.WriteString("class ")
 	builder.WriteString(c.Name.Lexeme)
+	if c.Superclass != nil {
+		builder.WriteString(" < ")
+		builder.WriteString(c.Superclass.Token.Lexeme)
+	}
 	builder.WriteString(" {\n")
 	for _, method := range c.Methods {
 		builder.WriteString(p.PrintStmt(method))
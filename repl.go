@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/printer"
+	"github.com/taki-mekhalfa/golox/repl"
+	"github.com/taki-mekhalfa/golox/scanner"
+	"github.com/taki-mekhalfa/golox/value"
+)
+
+const help = `Type Lox statements or expressions, the value of an expression is printed.
+Lines are read until the brackets, strings and comments are closed.
+
+Commands:
+  :help          show this help
+  :env           list the global variables
+  :load <file>   run a file in the current session
+  :reset         forget the global variables
+  :ast <code>    print the syntax tree of the code
+  :tokens <code> print the tokens of the code
+  :history       show the last entries of the history`
+
+// historySize is the number of history lines shown by :history
+const historySize = 20
+
+// stdin is shared by the prompt and the scripts, scripts
+// read their input from the lines typed after the one running them
+var stdin = bufio.NewReader(os.Stdin)
+
+func runPrompt() {
+	interpreter_.Stdin, vm_.Stdin = stdin, stdin
+	for {
+		entry, ok := readEntry()
+		if !ok {
+			break
+		}
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		saveHistory(entry)
+
+		if strings.HasPrefix(strings.TrimSpace(entry), ":") {
+			command(strings.TrimSpace(entry))
+			continue
+		}
+		// an interrupt stops the entry being run instead of the prompt
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		eval(ctx, entry)
+		stop()
+	}
+}
+
+// readEntry reads lines until the brackets, strings and comments
+// they open are closed, ok is false at the end of the input
+func readEntry() (entry string, ok bool) {
+	prompt := ">> "
+	for {
+		fmt.Print(prompt)
+		line, err := stdin.ReadString('\n')
+		if err != nil && err != io.EOF {
+			panic(err)
+		}
+		if err == io.EOF && line == "" {
+			if entry != "" {
+				// drop the unfinished entry
+				fmt.Println()
+			}
+			return "", false
+		}
+		entry += line
+		// commands are a single line
+		if strings.HasPrefix(strings.TrimSpace(entry), ":") || !repl.Incomplete(entry) {
+			return strings.TrimRight(entry, "\r\n"), true
+		}
+		prompt = ".. "
+	}
+}
+
+// eval runs an entry and prints the value of its
+// last statement if it is an expression statement
+func eval(ctx context.Context, entry string) {
+	stmts, err := repl.Parse(entry)
+	if err == nil {
+		err = resolve(stmts)
+	}
+	if err == nil {
+		// an entry holding only comments has no statements
+		if len(stmts) > 0 {
+			if es, ok := stmts[len(stmts)-1].(*ast.ExprStmt); ok {
+				stmts[len(stmts)-1] = &ast.Print{Pos: es.Pos, Expr: es.Expr}
+			}
+		}
+		err = execute(ctx, stmts)
+	}
+	if err != nil {
+		report(err, "", entry)
+	}
+}
+
+// command runs a meta-command of the prompt
+func command(line string) {
+	name, arg := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		name, arg = line[:i], strings.TrimSpace(line[i+1:])
+	}
+
+	switch name {
+	case ":help":
+		fmt.Println(help)
+	case ":env":
+		globals := vm_.Globals()
+		if !*useVM {
+			globals = interpreter_.Globals()
+		}
+		names := make([]string, 0, len(globals))
+		for name := range globals {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s = %s\n", name, value.Stringify(globals[name]))
+		}
+	case ":load":
+		load(arg)
+	case ":reset":
+		setup()
+		interpreter_.Stdin, vm_.Stdin = stdin, stdin
+	case ":ast":
+		printAST(arg)
+	case ":tokens":
+		scanner := scanner.Scanner{}
+		scanner.Init(arg)
+		if err := scanner.Scan(); err != nil {
+			report(err, "", arg)
+			return
+		}
+		tokens := scanner.Tokens()
+		for _, t := range tokens[:len(tokens)-1] {
+			fmt.Println(t)
+		}
+	case ":history":
+		lines := readHistory()
+		if len(lines) > historySize {
+			lines = lines[len(lines)-historySize:]
+		}
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+	default:
+		fmt.Fprintf(diagnostics, "Unknown command %s, type :help for the list of commands.\n", name)
+	}
+}
+
+// load runs the file in the current session, its
+// imports are relative to the file's directory
+func load(file string) {
+	if file == "" {
+		fmt.Fprintln(diagnostics, "Usage: :load <file>")
+		return
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(diagnostics, "Could not read the source file: %+v\n", err)
+		return
+	}
+
+	interpreter_.File, vm_.File = file, file
+	defer func() { interpreter_.File, vm_.File = "", "" }()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	_ = run(ctx, file, string(b))
+}
+
+// printAST prints the syntax tree of each statement of code
+func printAST(code string) {
+	stmts, err := repl.Parse(code)
+	if err != nil {
+		report(err, "", code)
+		return
+	}
+	for _, stmt := range stmts {
+		fmt.Println(printer.PrettyPrinter{}.PrintStmt(stmt))
+	}
+}
+
+// historyFile is the file the entries typed at the prompt are saved to,
+// $GOLOX_HISTORY or .golox_history in the home directory
+func historyFile() string {
+	if file := os.Getenv("GOLOX_HISTORY"); file != "" {
+		return file
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".golox_history")
+}
+
+// saveHistory appends an entry to the history file, the
+// history is best effort and its errors are ignored
+func saveHistory(entry string) {
+	file := historyFile()
+	if file == "" {
+		return
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, entry)
+}
+
+// readHistory returns the lines of the history file
+func readHistory() []string {
+	file := historyFile()
+	if file == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
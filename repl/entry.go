@@ -0,0 +1,94 @@
+// Package repl reads the entries typed at the prompt: an entry goes on
+// while it leaves a bracket, a string or a comment open, and the
+// semicolon ending its last statement can be left out.
+package repl
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/parser"
+	"github.com/taki-mekhalfa/golox/scanner"
+	"github.com/taki-mekhalfa/golox/token"
+)
+
+// Incomplete reports whether code opens brackets, a
+// string or a comment it doesn't close
+func Incomplete(code string) bool {
+	scanner := scanner.Scanner{}
+	scanner.Init(code)
+	if err := scanner.Scan(); err != nil {
+		for _, d := range err.(diag.List) {
+			if d.Code == diag.UnterminatedString || d.Code == diag.UnterminatedComment {
+				return true
+			}
+		}
+		return false
+	}
+
+	depth := 0
+	for _, t := range scanner.Tokens() {
+		switch t.Type {
+		case token.LEFT_PAREN, token.LEFT_BRACE, token.LEFT_BRACKET:
+			depth++
+		case token.RIGHT_PAREN, token.RIGHT_BRACE, token.RIGHT_BRACKET:
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// Parse parses an entry into statements. an entry that doesn't parse as
+// typed is parsed with a semicolon after its last token, then as a
+// single expression, so that '{"a": 1}["a"]' is a map and not a block.
+// the errors are those of the entry as typed.
+func Parse(code string) ([]ast.Stmt, error) {
+	scanner := scanner.Scanner{}
+	scanner.Init(code)
+	if err := scanner.Scan(); err != nil {
+		return nil, err
+	}
+	tokens := scanner.Tokens()
+	stmts, err := parse(tokens)
+	// the last token is EOF
+	if err == nil || len(tokens) < 2 {
+		return stmts, err
+	}
+
+	if terminated, err := parse(terminate(tokens)); err == nil {
+		return terminated, nil
+	}
+	parser := parser.Parser{}
+	parser.Init(tokens)
+	if expr, err := parser.ParseExpr(); err == nil {
+		return []ast.Stmt{&ast.ExprStmt{Pos: ast.Pos{Span: expr.Position()}, Expr: expr}}, nil
+	}
+	return stmts, err
+}
+
+func parse(tokens []token.Token) ([]ast.Stmt, error) {
+	parser := parser.Parser{}
+	parser.Init(tokens)
+	return parser.Parse()
+}
+
+// terminate inserts a semicolon right after the last token
+// of tokens, before the comments that may follow it
+func terminate(tokens []token.Token) []token.Token {
+	last, eof := tokens[len(tokens)-2], tokens[len(tokens)-1]
+	semicolon := token.Token{
+		Type:   token.SEMICOLON,
+		Lexeme: ";",
+		Line:   last.Line + strings.Count(last.Lexeme, "\n"),
+		Column: last.Column + utf8.RuneCountInString(last.Lexeme),
+		Offset: last.Offset + len(last.Lexeme),
+		File:   last.File,
+	}
+	if i := strings.LastIndexByte(last.Lexeme, '\n'); i >= 0 {
+		semicolon.Column = utf8.RuneCountInString(last.Lexeme[i+1:]) + 1
+	}
+	terminated := append([]token.Token{}, tokens[:len(tokens)-1]...)
+	return append(terminated, semicolon, eof)
+}
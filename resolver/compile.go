@@ -0,0 +1,64 @@
+package resolver
+
+import (
+	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/interpreter"
+	"github.com/taki-mekhalfa/golox/parser"
+	"github.com/taki-mekhalfa/golox/scanner"
+)
+
+// Compile scans, parses and resolves the code of file into statements
+// ready to be run, the distances of the variables are given to interp
+// unless it is nil, as for the bytecode compiler
+func Compile(file, src string, interp *interpreter.Interpreter) ([]ast.Stmt, error) {
+	scanner := scanner.Scanner{File: file}
+	scanner.Init(src)
+	if err := scanner.Scan(); err != nil {
+		return nil, err
+	}
+
+	parser := parser.Parser{}
+	parser.Init(scanner.Tokens())
+	stmts, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := Resolver{Interp: interp}
+	if err := resolver.Resolve(stmts); err != nil {
+		return nil, err
+	}
+	return stmts, nil
+}
+
+// CompileExpr compiles an expression evaluated by a debugger, resolved
+// in the scopes declaring names, the outermost first, as the
+// interpreter.Debugger's Compile hook does
+func CompileExpr(code string, interp *interpreter.Interpreter, scopes [][]string) (ast.Expr, error) {
+	scanner := scanner.Scanner{}
+	scanner.Init(code + ";")
+	if err := scanner.Scan(); err != nil {
+		return nil, err
+	}
+	parser := parser.Parser{}
+	parser.Init(scanner.Tokens())
+	stmts, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	var es *ast.ExprStmt
+	if len(stmts) == 1 {
+		es, _ = stmts[0].(*ast.ExprStmt)
+	}
+	if es == nil {
+		return nil, diag.List{{Phase: diag.Parse, Code: diag.ExpectedExpression, Message: "Expected an expression."}}
+	}
+
+	resolver := Resolver{Interp: interp}
+	resolver.Enclose(scopes...)
+	if err := resolver.Resolve(stmts); err != nil {
+		return nil, err
+	}
+	return es.Expr, nil
+}
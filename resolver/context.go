@@ -7,3 +7,11 @@ const (
 	function
 	initializer
 )
+
+type classCtx int
+
+const (
+	noClass classCtx = iota
+	class
+	subclass
+)
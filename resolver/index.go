@@ -0,0 +1,163 @@
+package resolver
+
+import (
+	"math"
+
+	"github.com/taki-mekhalfa/golox/token"
+)
+
+// Kind is the kind of a declaration
+type Kind int
+
+const (
+	VariableDecl Kind = iota
+	ParameterDecl
+	FunctionDecl
+	ClassDecl
+	MethodDecl
+	ModuleDecl
+)
+
+func (k Kind) String() string {
+	switch k {
+	case ParameterDecl:
+		return "parameter"
+	case FunctionDecl:
+		return "function"
+	case ClassDecl:
+		return "class"
+	case MethodDecl:
+		return "method"
+	case ModuleDecl:
+		return "module"
+	default:
+		return "variable"
+	}
+}
+
+// Declaration is a name declared in the code and the places referring to it
+type Declaration struct {
+	Name string
+	Kind Kind
+	// Span is the span of the declared name
+	Span token.Span
+	// Scope is the range of code the declaration is visible
+	// in, globals are visible in the whole code
+	Scope token.Span
+	// References are the spans of the variables and
+	// assignments referring to the declaration
+	References []token.Span
+}
+
+// Symbols records the declarations of the code a resolver resolves and
+// the references to them, for the tools working on the code. methods
+// and properties are looked up at runtime and have no references.
+type Symbols struct {
+	Declarations []*Declaration
+
+	globals map[string]*Declaration
+	// unresolved are the references to globals, which
+	// can be declared after the code referring to them
+	unresolved []reference
+	// free are the references to names the code doesn't
+	// declare, the built-ins and the undefined globals
+	free []reference
+}
+
+type reference struct {
+	name string
+	span token.Span
+}
+
+// At returns the declaration whose name or one of its
+// references is at offset, nil if there is none
+func (s *Symbols) At(offset int) *Declaration {
+	for _, d := range s.Declarations {
+		if contains(d.Span, offset) {
+			return d
+		}
+		for _, ref := range d.References {
+			if contains(ref, offset) {
+				return d
+			}
+		}
+	}
+	return nil
+}
+
+// Visible returns the declarations visible at offset,
+// the innermost one when a name is shadowed
+func (s *Symbols) Visible(offset int) []*Declaration {
+	visible := map[string]*Declaration{}
+	var names []string
+	for _, d := range s.Declarations {
+		if d.Kind == MethodDecl || offset < d.Scope.Offset || offset >= d.Scope.End {
+			continue
+		}
+		shadowed, ok := visible[d.Name]
+		if !ok {
+			names = append(names, d.Name)
+		}
+		if !ok || d.Span.Offset > shadowed.Span.Offset {
+			visible[d.Name] = d
+		}
+	}
+	decls := make([]*Declaration, len(names))
+	for i, name := range names {
+		decls[i] = visible[name]
+	}
+	return decls
+}
+
+// Free returns the spans of the references to name
+// when the code doesn't declare it, such as a built-in
+func (s *Symbols) Free(name string) []token.Span {
+	var spans []token.Span
+	for _, ref := range s.free {
+		if ref.name == name {
+			spans = append(spans, ref.span)
+		}
+	}
+	return spans
+}
+
+// declare records a declaration visible until the end of scope
+func (s *Symbols) declare(name string, kind Kind, at token.Span, scope token.Span) *Declaration {
+	d := &Declaration{Name: name, Kind: kind, Span: at, Scope: scope}
+	d.Scope.Offset = at.Offset
+	s.Declarations = append(s.Declarations, d)
+	return d
+}
+
+// declareGlobal records a declaration of the global scope
+func (s *Symbols) declareGlobal(name string, kind Kind, at token.Span) {
+	if s.globals == nil {
+		s.globals = map[string]*Declaration{}
+	}
+	// a global can be declared again, references go to the first declaration
+	if _, ok := s.globals[name]; ok {
+		return
+	}
+	d := s.declare(name, kind, at, token.Span{End: math.MaxInt})
+	d.Scope.Offset = 0
+	s.globals[name] = d
+}
+
+// resolveGlobals adds the references to globals
+// to their declarations once they are all known
+func (s *Symbols) resolveGlobals() {
+	for _, ref := range s.unresolved {
+		if d, ok := s.globals[ref.name]; ok {
+			d.References = append(d.References, ref.span)
+		} else {
+			s.free = append(s.free, ref)
+		}
+	}
+	s.unresolved = nil
+}
+
+// contains reports whether offset is in span or right after it,
+// where editors put the cursor after typing a name
+func contains(span token.Span, offset int) bool {
+	return offset >= span.Offset && offset <= span.End
+}
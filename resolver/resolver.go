@@ -2,9 +2,12 @@ package resolver
 
 import (
 	"fmt"
+	"sort"
 
 	. "github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
 	"github.com/taki-mekhalfa/golox/interpreter"
+	"github.com/taki-mekhalfa/golox/token"
 )
 
 const (
@@ -14,24 +17,52 @@ const (
 type meta struct {
 	defined bool
 	used    bool
-	line    int
+	// span is where the variable is declared
+	span token.Span
+	// decl is the declaration recorded in the symbols
+	decl *Declaration
 }
 
 type Resolver struct {
-	Error      func(line int, errMessage string)
-	ErrorCount int
+	diags diag.List
 
 	scopes []map[string]*meta
+	// spans are the ranges of code of the scopes
+	spans  []token.Span
 	Interp *interpreter.Interpreter
+	// Symbols records the declarations and the
+	// references to them when it is not nil
+	Symbols *Symbols
 
-	funcCtx     functionCtx
-	insideClass bool
+	funcCtx  functionCtx
+	classCtx classCtx
+	// number of loops enclosing the current statement
+	// inside the current function
+	loopDepth int
 }
 
-func (r *Resolver) Resolve(stmts []Stmt) {
+// Resolve resolves the variables of stmts, the returned
+// error is a diag.List of the semantic errors.
+func (r *Resolver) Resolve(stmts []Stmt) error {
 	for _, stmt := range stmts {
 		r.resolveStmt(stmt)
 	}
+	if r.Symbols != nil {
+		r.Symbols.resolveGlobals()
+	}
+	return r.diags.Err()
+}
+
+// Enclose makes the code resolved next nested in scopes declaring
+// names, the outermost first. it is used to run code in the
+// environment of a paused execution.
+func (r *Resolver) Enclose(scopes ...[]string) {
+	for _, names := range scopes {
+		r.beginScope(token.Span{})
+		for _, name := range names {
+			r.currentScope()[name] = &meta{defined: true, used: true}
+		}
+	}
 }
 
 func (r *Resolver) VisitGet(g *Get) (void interface{}) {
@@ -50,15 +81,33 @@ func (r *Resolver) VisitSet(s *Set) (void interface{}) {
 }
 
 func (r *Resolver) VisitClass(c *Class) (void interface{}) {
-	r.declare(c.Name.Lexeme, c.Name.Line)
+	r.declare(c.Name.Lexeme, token.SpanOf(c.Name))
+	r.record(c.Name.Lexeme, token.SpanOf(c.Name), ClassDecl)
 	r.define(c.Name.Lexeme)
 
-	r.beginScope()
+	enclosingClassCtx := r.classCtx
+	r.classCtx = class
 
-	enclosedInClass := r.insideClass
-	r.insideClass = true
+	if c.Superclass != nil {
+		if c.Superclass.Token.Lexeme == c.Name.Lexeme {
+			r.reportError(token.SpanOf(c.Superclass.Token), diag.InheritFromSelf, "A class can't inherit from itself.")
+		}
+		r.resolveExpr(c.Superclass)
+		r.classCtx = subclass
+
+		// the superclass is bound to "super" in a scope
+		// surrounding the one holding "this".
+		r.beginScope(c.Position())
+		r.declare("super", token.SpanOf(c.Name))
+		r.define("super")
+		// "super" is used by default to avoid errors
+		// related to declared but not used variables
+		r.use("super")
+	}
+
+	r.beginScope(c.Position())
 
-	r.declare("this", c.Name.Line)
+	r.declare("this", token.SpanOf(c.Name))
 	r.define("this")
 	// "this" is used by default to avoid errors
 	// related to declared but not used variables
@@ -71,7 +120,7 @@ func (r *Resolver) VisitClass(c *Class) (void interface{}) {
 		} else {
 			r.funcCtx = function
 		}
-		r.reslveFunction(method)
+		r.reslveFunction(method, MethodDecl)
 		r.funcCtx = enclosingFuncCtx
 
 		// methods are used by default to avoid errors
@@ -81,12 +130,16 @@ func (r *Resolver) VisitClass(c *Class) (void interface{}) {
 
 	r.endScope()
 
-	r.insideClass = enclosedInClass
+	if c.Superclass != nil {
+		r.endScope()
+	}
+
+	r.classCtx = enclosingClassCtx
 	return
 }
 
 func (r *Resolver) VisitBlock(b *Block) (void interface{}) {
-	r.beginScope()
+	r.beginScope(b.Position())
 	for _, stmt := range b.Content {
 		r.resolveStmt(stmt)
 	}
@@ -95,7 +148,8 @@ func (r *Resolver) VisitBlock(b *Block) (void interface{}) {
 }
 
 func (r *Resolver) VisitVarStmt(var_ *VarStmt) (void interface{}) {
-	r.declare(var_.Name, var_.Token.Line)
+	r.declare(var_.Name, token.SpanOf(var_.Token))
+	r.record(var_.Name, token.SpanOf(var_.Token), VariableDecl)
 	if var_.Initializer != nil {
 		r.resolveExpr(var_.Initializer)
 	}
@@ -103,28 +157,51 @@ func (r *Resolver) VisitVarStmt(var_ *VarStmt) (void interface{}) {
 	return nil
 }
 
+func (r *Resolver) VisitImport(i *Import) (void interface{}) {
+	r.declare(i.Name.Lexeme, token.SpanOf(i.Name))
+	r.record(i.Name.Lexeme, token.SpanOf(i.Name), ModuleDecl)
+	r.define(i.Name.Lexeme)
+	return
+}
+
 func (r *Resolver) VisitVar(var_ *Var) (void interface{}) {
 	if meta, declared := r.currentScope()[var_.Token.Lexeme]; declared && !meta.defined {
-		r.reportError(var_.Token.Line, "Can't read local variable in its own initializer.")
+		r.reportError(token.SpanOf(var_.Token), diag.ReadInOwnInitializer, "Can't read local variable in its own initializer.")
 	}
 	r.use(var_.Token.Lexeme)
+	r.refer(var_.Token.Lexeme, token.SpanOf(var_.Token))
 	r.resolve(var_, var_.Token.Lexeme)
 	return
 }
 
 func (r *Resolver) VisitAssign(a *Assign) (void interface{}) {
 	r.resolveExpr(a.Value)
+	r.refer(a.Identifier.Lexeme, token.SpanOf(a.Identifier))
 	r.resolve(a, a.Identifier.Lexeme)
 	return
 }
 
-func (r *Resolver) reslveFunction(f *Function) {
-	r.declare(f.Name.Lexeme, f.Name.Line)
+func (r *Resolver) reslveFunction(f *Function, kind Kind) {
+	r.declare(f.Name.Lexeme, token.SpanOf(f.Name))
+	r.record(f.Name.Lexeme, token.SpanOf(f.Name), kind)
 	r.define(f.Name.Lexeme)
 
-	r.beginScope()
+	r.resolveFunctionBody(f)
+}
+
+// resolveFunctionBody resolves the parameters and the body
+// of a function in a new scope
+func (r *Resolver) resolveFunctionBody(f *Function) {
+	// a function body starts outside of any loop,
+	// 'break' and 'continue' can't cross its boundary
+	enclosingLoopDepth := r.loopDepth
+	r.loopDepth = 0
+	defer func() { r.loopDepth = enclosingLoopDepth }()
+
+	r.beginScope(f.Position())
 	for _, param := range f.Params {
-		r.declare(param.Lexeme, f.Name.Line)
+		r.declare(param.Lexeme, token.SpanOf(param))
+		r.record(param.Lexeme, token.SpanOf(param), ParameterDecl)
 		r.define(param.Lexeme)
 	}
 	for _, stmt := range f.Body {
@@ -137,7 +214,18 @@ func (r *Resolver) VisitFunction(f *Function) (void interface{}) {
 	enclosingFuncCtx := r.funcCtx
 	r.funcCtx = function
 
-	r.reslveFunction(f)
+	r.reslveFunction(f, FunctionDecl)
+
+	r.funcCtx = enclosingFuncCtx
+	return
+}
+
+func (r *Resolver) VisitLambda(l *Lambda) (void interface{}) {
+	enclosingFuncCtx := r.funcCtx
+	r.funcCtx = function
+
+	// a lambda has no name to declare
+	r.resolveFunctionBody(l.Function)
 
 	r.funcCtx = enclosingFuncCtx
 	return
@@ -166,11 +254,11 @@ func (r *Resolver) VisitReturn(ret_ *Return) (void interface{}) {
 	switch r.funcCtx {
 	case initializer:
 		if ret_.Value != nil {
-			r.reportError(ret_.Token.Line, "Can't return a value from class initializer.")
+			r.reportError(token.SpanOf(ret_.Token), diag.ReturnFromInitializer, "Can't return a value from class initializer.")
 			return
 		}
-	case function:
-		r.reportError(ret_.Token.Line, "Can't return from top-level code.")
+	case none:
+		r.reportError(token.SpanOf(ret_.Token), diag.ReturnOutsideFunction, "Can't return from top-level code.")
 		return
 	default:
 	}
@@ -183,7 +271,52 @@ func (r *Resolver) VisitReturn(ret_ *Return) (void interface{}) {
 
 func (r *Resolver) VisitWhile(while *While) (void interface{}) {
 	r.resolveExpr(while.Condition)
+	r.loopDepth++
 	r.resolveStmt(while.Body)
+	r.loopDepth--
+	if while.Increment != nil {
+		r.resolveExpr(while.Increment)
+	}
+	return
+}
+
+func (r *Resolver) VisitThrow(t *Throw) (void interface{}) {
+	r.resolveExpr(t.Value)
+	return
+}
+
+func (r *Resolver) VisitTry(t *Try) (void interface{}) {
+	r.resolveStmt(t.Body)
+	if t.Catch != nil {
+		// the caught value is bound in a scope
+		// surrounding the catch block
+		r.beginScope(t.Catch.Position())
+		r.declare(t.CatchParam.Lexeme, token.SpanOf(t.CatchParam))
+		r.record(t.CatchParam.Lexeme, token.SpanOf(t.CatchParam), VariableDecl)
+		r.define(t.CatchParam.Lexeme)
+		// the caught value is used by default as it is
+		// often ignored when recovering from an error
+		r.use(t.CatchParam.Lexeme)
+		r.resolveStmt(t.Catch)
+		r.endScope()
+	}
+	if t.Finally != nil {
+		r.resolveStmt(t.Finally)
+	}
+	return
+}
+
+func (r *Resolver) VisitBreak(b *Break) (void interface{}) {
+	if r.loopDepth == 0 {
+		r.reportError(token.SpanOf(b.Keyword), diag.BreakOutsideLoop, "Can't use 'break' outside of a loop.")
+	}
+	return
+}
+
+func (r *Resolver) VisitContinue(c *Continue) (void interface{}) {
+	if r.loopDepth == 0 {
+		r.reportError(token.SpanOf(c.Keyword), diag.ContinueOutsideLoop, "Can't use 'continue' outside of a loop.")
+	}
 	return
 }
 
@@ -201,6 +334,45 @@ func (r *Resolver) VisitCall(call *Call) (void interface{}) {
 	return
 }
 
+func (r *Resolver) VisitList(l *List) (void interface{}) {
+	for _, element := range l.Elements {
+		r.resolveExpr(element)
+	}
+	return
+}
+
+func (r *Resolver) VisitIndex(i *Index) (void interface{}) {
+	r.resolveExpr(i.Object)
+	r.resolveExpr(i.Index)
+	return
+}
+
+func (r *Resolver) VisitSlice(s *Slice) (void interface{}) {
+	r.resolveExpr(s.Object)
+	if s.Start != nil {
+		r.resolveExpr(s.Start)
+	}
+	if s.End != nil {
+		r.resolveExpr(s.End)
+	}
+	return
+}
+
+func (r *Resolver) VisitSetIndex(s *SetIndex) (void interface{}) {
+	r.resolveExpr(s.Value)
+	r.resolveExpr(s.Object)
+	r.resolveExpr(s.Index)
+	return
+}
+
+func (r *Resolver) VisitMap(m *Map) (void interface{}) {
+	for i := range m.Keys {
+		r.resolveExpr(m.Keys[i])
+		r.resolveExpr(m.Values[i])
+	}
+	return
+}
+
 func (r *Resolver) VisitGrouping(g *Grouping) (void interface{}) {
 	r.resolveExpr(g.Expr)
 	return
@@ -222,41 +394,89 @@ func (r *Resolver) VisitUnary(u *Unary) (void interface{}) {
 }
 
 func (r *Resolver) VisitThis(this *This) (void interface{}) {
-	if !r.insideClass {
-		r.reportError(this.Keyword.Line, "Can't use 'this' outside of a class.")
+	if r.classCtx == noClass {
+		r.reportError(token.SpanOf(this.Keyword), diag.ThisOutsideClass, "Can't use 'this' outside of a class.")
 		return
 	}
 	r.resolve(this, "this")
 	return
 }
 
+func (r *Resolver) VisitSuper(s *Super) (void interface{}) {
+	switch r.classCtx {
+	case noClass:
+		r.reportError(token.SpanOf(s.Keyword), diag.SuperOutsideClass, "Can't use 'super' outside of a class.")
+		return
+	case class:
+		r.reportError(token.SpanOf(s.Keyword), diag.SuperWithoutSuperclass, "Can't use 'super' in a class with no superclass.")
+		return
+	}
+	r.resolve(s, "super")
+	return
+}
+
 func (r *Resolver) resolve(expr Expr, name string) {
 	for i := len(r.scopes) - 1; i >= 0; i-- {
 		if _, ok := r.scopes[i][name]; ok {
-			r.Interp.Resolve(expr, len(r.scopes)-i-1)
+			// the bytecode compiler tracks scopes on its own
+			if r.Interp != nil {
+				r.Interp.Resolve(expr, len(r.scopes)-i-1)
+			}
 			return
 		}
 	}
 }
 
+// use marks the variable name refers to from the current scope as used
 func (r *Resolver) use(name string) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if meta, ok := r.scopes[i][name]; ok {
+			meta.used = true
+			return
+		}
+	}
+}
+
+// refer records a reference to the variable name in the symbols
+func (r *Resolver) refer(name string, at token.Span) {
+	if r.Symbols == nil {
+		return
+	}
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if meta, ok := r.scopes[i][name]; ok {
+			if meta.decl != nil {
+				meta.decl.References = append(meta.decl.References, at)
+			}
+			return
+		}
+	}
+	r.Symbols.unresolved = append(r.Symbols.unresolved, reference{name, at})
+}
+
+// record adds the declaration of name in the current scope to the symbols
+func (r *Resolver) record(name string, at token.Span, kind Kind) {
+	if r.Symbols == nil {
+		return
+	}
 	if r.currentScope() == nil {
+		r.Symbols.declareGlobal(name, kind, at)
 		return
 	}
-	if _, ok := r.currentScope()[name]; ok {
-		r.currentScope()[name].used = true
+	// a name declared twice in a scope keeps its first declaration
+	if meta := r.currentScope()[name]; meta.decl == nil {
+		meta.decl = r.Symbols.declare(name, kind, at, r.spans[len(r.spans)-1])
 	}
 }
 
-func (r *Resolver) declare(name string, line int) {
+func (r *Resolver) declare(name string, at token.Span) {
 	if r.currentScope() == nil {
 		return
 	}
 	if _, ok := r.currentScope()[name]; ok {
-		r.reportError(line, "Already a variable with this name in this scope.")
+		r.reportError(at, diag.AlreadyDeclared, "Already a variable with this name in this scope.")
 		return
 	}
-	r.currentScope()[name] = &meta{line: line}
+	r.currentScope()[name] = &meta{span: at}
 }
 
 func (r *Resolver) define(name string) {
@@ -275,17 +495,28 @@ func (r *Resolver) resolveExpr(expr Expr) interface{} {
 	return expr.Accept(r)
 }
 
-func (r *Resolver) beginScope() {
+// beginScope starts the scope of the code in span
+func (r *Resolver) beginScope(span token.Span) {
 	r.scopes = append(r.scopes, map[string]*meta{})
+	r.spans = append(r.spans, span)
 }
 
 func (r *Resolver) endScope() {
+	// report the unused variables in the order they were declared
+	var unused []string
 	for name, meta := range r.currentScope() {
 		if !meta.used {
-			r.reportError(meta.line, fmt.Sprintf("%s declared but not used.", name))
+			unused = append(unused, name)
 		}
 	}
+	sort.Slice(unused, func(i, j int) bool {
+		return r.currentScope()[unused[i]].span.Offset < r.currentScope()[unused[j]].span.Offset
+	})
+	for _, name := range unused {
+		r.reportError(r.currentScope()[name].span, diag.UnusedVariable, fmt.Sprintf("%s declared but not used.", name))
+	}
 	r.scopes = r.scopes[:len(r.scopes)-1]
+	r.spans = r.spans[:len(r.spans)-1]
 }
 
 func (r *Resolver) currentScope() map[string]*meta {
@@ -295,7 +526,6 @@ func (r *Resolver) currentScope() map[string]*meta {
 	return r.scopes[len(r.scopes)-1]
 }
 
-func (r *Resolver) reportError(line int, errMessage string) {
-	r.ErrorCount++
-	r.Error(line, errMessage)
+func (r *Resolver) reportError(span token.Span, code diag.Code, errMessage string) {
+	r.diags = append(r.diags, diag.New(diag.Resolve, span, code, errMessage))
 }
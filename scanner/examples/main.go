@@ -7,9 +7,7 @@ import (
 )
 
 func main() {
-	scanner := scanner.Scanner{Error: func(line int, errMessage string) {
-		fmt.Printf("[line %d] Error: %s\n", line, errMessage)
-	}}
+	scanner := scanner.Scanner{}
 
 	src := `
 		// THIS IS A COMMENT
@@ -25,6 +23,8 @@ func main() {
 	`
 
 	scanner.Init(src)
-	scanner.Scan()
+	if err := scanner.Scan(); err != nil {
+		fmt.Println(err)
+	}
 	fmt.Println(scanner.Tokens())
 }
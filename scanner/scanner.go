@@ -1,18 +1,34 @@
 package scanner
 
-import "github.com/taki-mekhalfa/golox/token"
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/token"
+)
 
 type Scanner struct {
 	src string
+	// File is the path of the scanned source,
+	// it is recorded in the scanned tokens.
+	File string
 
-	Error      func(line int, errMessage string)
-	ErrorCount int
+	diags diag.List
 
 	line       int
 	startPos   int
 	currentPos int
+	// lineStart is the offset of the current line
+	lineStart int
+	// startLine and startColumn locate the token being scanned
+	startLine   int
+	startColumn int
 
 	tokens []token.Token
+	// comments are the comments scanned since the last token
+	comments []token.Comment
 }
 
 const (
@@ -32,10 +48,14 @@ func (s *Scanner) Tokens() []token.Token {
 	return s.tokens
 }
 
-func (s *Scanner) Scan() {
+// Scan scans the source into tokens, the returned
+// error is a diag.List of the scanning errors.
+func (s *Scanner) Scan() error {
 	// while we did not consume the entire source
 	for !s.isAtEnd() {
 		s.startPos = s.currentPos
+		s.startLine = s.line
+		s.startColumn = s.column(s.startPos)
 
 		c := s.next()
 		switch c {
@@ -47,8 +67,14 @@ func (s *Scanner) Scan() {
 			s.appendToken(token.LEFT_BRACE)
 		case '}':
 			s.appendToken(token.RIGHT_BRACE)
+		case '[':
+			s.appendToken(token.LEFT_BRACKET)
+		case ']':
+			s.appendToken(token.RIGHT_BRACKET)
 		case ',':
 			s.appendToken(token.COMMA)
+		case ':':
+			s.appendToken(token.COLON)
 		case '.':
 			s.appendToken(token.DOT)
 		case '-':
@@ -86,20 +112,20 @@ func (s *Scanner) Scan() {
 			}
 		case '/':
 			if s.match('/') {
-				// Ignore line comments
 				for s.peek() != NL && !s.isAtEnd() {
 					s.next()
 				}
+				s.appendComment()
 			} else if s.match('*') {
-				// Ignore multiline comments
 				s.scanMultiLineComments()
+				s.appendComment()
 			} else {
 				s.appendToken(token.SLASH)
 			}
 		case SPACE, TAB, CR:
 			// Ignore white space
 		case NL:
-			s.line++
+			s.newLine()
 		case '"':
 			s.scanString()
 		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
@@ -109,38 +135,132 @@ func (s *Scanner) Scan() {
 			case isAlpha(c):
 				s.scanIdentifier()
 			default:
-				s.ErrorCount++
-				s.Error(s.line, "Unexpected character.")
+				s.reportError(diag.UnexpectedCharacter, "Unexpected character.")
 			}
 		}
 	}
 
-	s.tokens = append(s.tokens, token.Token{Type: token.EOF, Lexeme: "", Line: s.line})
+	s.tokens = append(s.tokens, token.Token{
+		Type:     token.EOF,
+		Lexeme:   "",
+		Line:     s.line,
+		Column:   s.column(s.currentPos),
+		Offset:   s.currentPos,
+		File:     s.File,
+		Comments: s.comments,
+	})
+	return s.diags.Err()
 }
 
 func (s *Scanner) appendToken(typ token.Type) {
 	s.tokens = append(s.tokens, token.Token{
-		Type:   typ,
-		Line:   s.line,
-		Lexeme: s.src[s.startPos:s.currentPos],
+		Type:     typ,
+		Lexeme:   s.src[s.startPos:s.currentPos],
+		Line:     s.startLine,
+		Column:   s.startColumn,
+		Offset:   s.startPos,
+		File:     s.File,
+		Comments: s.comments,
 	})
+	s.comments = nil
+}
+
+// appendComment keeps the comment just scanned as trivia of the next token
+func (s *Scanner) appendComment() {
+	s.comments = append(s.comments, token.Comment{
+		Text: s.src[s.startPos:s.currentPos],
+		Span: token.Span{
+			File:   s.File,
+			Line:   s.startLine,
+			Column: s.startColumn,
+			Offset: s.startPos,
+			End:    s.currentPos,
+		},
+	})
+}
+
+func (s *Scanner) newLine() {
+	s.line++
+	s.lineStart = s.currentPos
 }
 
 func (s *Scanner) scanString() {
+	var literal strings.Builder
 	for {
 		if s.isAtEnd() {
-			s.ErrorCount++
-			s.Error(s.line, "Unterminated string.")
+			s.reportError(diag.UnterminatedString, "Unterminated string.")
 			break
 		}
 		next := s.next()
-		if next == NL {
-			s.line++
-		} else if next == '"' {
+		switch next {
+		case NL:
+			s.newLine()
+		case '"':
 			s.appendToken(token.STRING)
-			break
+			s.tokens[len(s.tokens)-1].Literal = literal.String()
+			return
+		case '\\':
+			escaped, ok := s.scanEscape()
+			if !ok {
+				continue
+			}
+			next = escaped
 		}
+		literal.WriteRune(next)
+	}
+}
+
+// escapes are the characters escaped by a backslash in a string
+var escapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'"':  '"',
+	'\\': '\\',
+}
+
+// scanEscape scans the escape sequence following a backslash and
+// returns the character it stands for, ok is false if it is invalid
+func (s *Scanner) scanEscape() (r rune, ok bool) {
+	start := s.currentPos - 1
+	// the string is reported as unterminated
+	if s.isAtEnd() {
+		return 0, false
+	}
+	if s.peek() == NL {
+		s.reportErrorAt(start, diag.InvalidEscape, "Invalid escape sequence.")
+		return 0, false
+	}
+	c := s.next()
+	if r, ok := escapes[c]; ok {
+		return r, true
+	}
+	if c != 'u' {
+		s.reportErrorAt(start, diag.InvalidEscape, "Invalid escape sequence.")
+		return 0, false
+	}
+
+	// \u{...} holds the 1 to 6 hexadecimal digits of a code point
+	if !s.match('{') {
+		s.reportErrorAt(start, diag.InvalidEscape, "Expected { after \\u.")
+		return 0, false
+	}
+	digits := s.currentPos
+	for isHexDigit(s.peek()) {
+		s.next()
+	}
+	hex := s.src[digits:s.currentPos]
+	if !s.match('}') {
+		s.reportErrorAt(start, diag.InvalidEscape, "Expected } after the code point.")
+		return 0, false
+	}
+	code, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) > 6 || !utf8.ValidRune(rune(code)) {
+		s.reportErrorAt(start, diag.InvalidEscape, "Invalid Unicode code point.")
+		return 0, false
 	}
+	return rune(code), true
 }
 
 func (s *Scanner) scanNumber() {
@@ -172,14 +292,13 @@ func (s *Scanner) scanIdentifier() {
 func (s *Scanner) scanMultiLineComments() {
 	for {
 		if s.isAtEnd() {
-			s.ErrorCount++
-			s.Error(s.line, "Unterminated multiline comment.")
+			s.reportError(diag.UnterminatedComment, "Unterminated multiline comment.")
 			break
 		}
 
 		next := s.next()
 		if next == NL {
-			s.line++
+			s.newLine()
 		} else if next == '*' && s.peek() == '/' {
 			s.next()
 			break
@@ -187,3 +306,34 @@ func (s *Scanner) scanMultiLineComments() {
 	}
 
 }
+
+// column returns the 1-based column of the offset in the
+// current line, counted in characters rather than bytes
+func (s *Scanner) column(offset int) int {
+	return utf8.RuneCountInString(s.src[s.lineStart:offset]) + 1
+}
+
+// reportError reports an error spanning the token being scanned
+func (s *Scanner) reportError(code diag.Code, errMessage string) {
+	span := token.Span{
+		File:   s.File,
+		Line:   s.startLine,
+		Column: s.startColumn,
+		Offset: s.startPos,
+		End:    s.currentPos,
+	}
+	s.diags = append(s.diags, diag.New(diag.Scan, span, code, errMessage))
+}
+
+// reportErrorAt reports an error spanning from the start offset
+// of the current line to the current position
+func (s *Scanner) reportErrorAt(start int, code diag.Code, errMessage string) {
+	span := token.Span{
+		File:   s.File,
+		Line:   s.line,
+		Column: s.column(start),
+		Offset: start,
+		End:    s.currentPos,
+	}
+	s.diags = append(s.diags, diag.New(diag.Scan, span, code, errMessage))
+}
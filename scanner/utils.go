@@ -1,28 +1,46 @@
 package scanner
 
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// isAlpha reports whether c can start an identifier,
+// identifiers can be written with letters of any script
 func isAlpha(c rune) bool {
-	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' ||
+		c >= utf8.RuneSelf && unicode.IsLetter(c)
 }
 
 func isDigit(c rune) bool {
 	return c >= '0' && c <= '9'
 }
 
+func isHexDigit(c rune) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// isAlphaNumeric reports whether c can continue an identifier,
+// the combining marks of accented letters are part of it
 func isAlphaNumeric(c rune) bool {
-	return isAlpha(c) || isDigit(c)
+	return isAlpha(c) || isDigit(c) || c >= utf8.RuneSelf && unicode.In(c, unicode.Mn, unicode.Mc, unicode.Nd)
 }
 
 func (s *Scanner) isAtEnd() bool {
 	return s.currentPos >= len(s.src)
 }
 
+// next consumes the character at the current position,
+// the source is decoded as UTF-8
 func (s *Scanner) next() rune {
-	s.currentPos++
-	return rune(s.src[s.currentPos-1])
+	c, size := utf8.DecodeRuneInString(s.src[s.currentPos:])
+	s.currentPos += size
+	return c
 }
 
 func (s *Scanner) current() rune {
-	return rune(s.src[s.currentPos])
+	c, _ := utf8.DecodeRuneInString(s.src[s.currentPos:])
+	return c
 }
 
 func (s *Scanner) peek() rune {
@@ -34,16 +52,21 @@ func (s *Scanner) peek() rune {
 }
 
 func (s *Scanner) peekNext() rune {
-	if s.currentPos+1 >= len(s.src) {
+	if s.isAtEnd() {
+		return EOF
+	}
+	_, size := utf8.DecodeRuneInString(s.src[s.currentPos:])
+	if s.currentPos+size >= len(s.src) {
 		return EOF
 	}
 
-	return rune(s.src[s.currentPos+1])
+	c, _ := utf8.DecodeRuneInString(s.src[s.currentPos+size:])
+	return c
 }
 
 func (s *Scanner) match(c rune) bool {
 	if !s.isAtEnd() && s.current() == c {
-		s.currentPos++
+		s.currentPos += utf8.RuneLen(c)
 		return true
 	}
 
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/taki-mekhalfa/golox/diag"
+)
+
+// the errors stopping the scripts go through the handlers
+const (
+	spinning = `try {
+  while (true) {}
+} catch (e) {
+  print "caught";
+} finally {
+  print "finally";
+}
+print "after";
+`
+	allocating = `var l = [];
+try {
+  while (true) push(l, [1]);
+} catch (e) {
+  print "caught";
+}
+print "after";
+`
+	// the lists, maps and strings built by the natives are allocations
+	natives = `var m = {"a": 1};
+var l = [];
+for (var i = 0; i < 100000; i = i + 1) push(l, keys(m));
+print "after";
+`
+)
+
+// TestBudgets checks that cancellation and exhausted budgets stop
+// the scripts with errors try, catch and finally can't swallow
+func TestBudgets(t *testing.T) {
+	tests := []struct {
+		name               string
+		src                string
+		steps, allocations int
+		timeout            time.Duration
+		code               diag.Code
+	}{
+		{"steps", spinning, 1000, 0, 0, diag.BudgetExceeded},
+		{"allocations", allocating, 0, 100, 0, diag.BudgetExceeded},
+		{"natives", natives, 0, 100, 0, diag.BudgetExceeded},
+		{"cancelled", spinning, 0, 0, 10 * time.Millisecond, diag.Cancelled},
+	}
+	for _, backend := range backends {
+		for _, test := range tests {
+			t.Run(test.name+"/"+backend.name, func(t *testing.T) {
+				ctx := context.Background()
+				if test.timeout != 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, test.timeout)
+					defer cancel()
+				}
+				var stdout bytes.Buffer
+				err := backend.run("", test.src, &stdout, func(o *options) {
+					o.ctx, o.maxSteps, o.maxAllocations = ctx, test.steps, test.allocations
+				})
+				diags, ok := err.(diag.List)
+				if !ok || len(diags) != 1 || diags[0].Code != test.code {
+					t.Fatalf("got %v, want a %s error", err, test.code)
+				}
+				if bytes.Contains(stdout.Bytes(), []byte("caught")) || bytes.Contains(stdout.Bytes(), []byte("after")) {
+					t.Errorf("the script went on after being stopped:\n%s", stdout.String())
+				}
+			})
+		}
+	}
+}
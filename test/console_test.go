@@ -0,0 +1,54 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/lox"
+)
+
+const reading = `var name = input("name? ");
+print "hi " + name;
+print readLine();
+print readLine();
+print nil + 1;
+`
+
+// TestConsole feeds the input of the scripts and checks that
+// they print their output apart from the diagnostics
+func TestConsole(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := backend.run("", reading, &stdout, func(o *options) { o.stdin = strings.NewReader("alice\r\nbob") })
+			// the line terminators are dropped and readLine returns nil at the end
+			if got, want := stdout.String(), "name? hi alice\nbob\nnil\n"; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+			diags, ok := err.(diag.List)
+			if !ok || len(diags) != 1 || diags[0].Line != 5 || diags[0].Code != diag.TypeError {
+				t.Errorf("got %v, want the type error of line 5", err)
+			}
+		})
+	}
+}
+
+func TestEmbedInput(t *testing.T) {
+	vm := lox.New()
+	var stdout bytes.Buffer
+	vm.SetOutput(&stdout)
+	vm.SetInput(strings.NewReader("first\nsecond\n"))
+	if v, err := vm.Eval("readLine();"); err != nil || v != "first" {
+		t.Errorf("got %v, %v, want first", v, err)
+	}
+	// the lines buffered from the previous input are dropped
+	vm.SetInput(strings.NewReader("other\n"))
+	if v, err := vm.Eval("readLine();"); err != nil || v != "other" {
+		t.Errorf("got %v, %v, want other", v, err)
+	}
+	if _, err := vm.Eval("print 1; print nil + 1;"); err == nil || stdout.String() != "1\n" {
+		t.Errorf("got %q, %v, want 1 printed and a diagnostic", stdout.String(), err)
+	}
+}
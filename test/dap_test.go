@@ -0,0 +1,304 @@
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/taki-mekhalfa/golox/dap"
+)
+
+// adapterClient talks to a debug adapter running in process
+type adapterClient struct {
+	t  *testing.T
+	in *io.PipeWriter
+	// messages are read from the adapter on their own goroutine
+	// so that its events never block the requests
+	messages chan map[string]interface{}
+	seq      int
+	// events are the events received and not waited for yet
+	events []map[string]interface{}
+	done   chan error
+}
+
+func startAdapter(t *testing.T) *adapterClient {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &adapterClient{t: t, in: inW, messages: make(chan map[string]interface{}, 64), done: make(chan error, 1)}
+	go c.read(textproto.NewReader(bufio.NewReader(outR)))
+	go func() {
+		err := dap.Serve(inR, outW)
+		outW.Close()
+		c.done <- err
+	}()
+	return c
+}
+
+// read reads the messages of the adapter until it closes its output
+func (c *adapterClient) read(out *textproto.Reader) {
+	defer close(c.messages)
+	for {
+		header, err := out.ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			return
+		}
+		content := make([]byte, length)
+		if _, err := io.ReadFull(out.R, content); err != nil {
+			return
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(content, &msg); err != nil {
+			return
+		}
+		c.messages <- msg
+	}
+}
+
+func (c *adapterClient) receive() map[string]interface{} {
+	c.t.Helper()
+	msg, ok := <-c.messages
+	if !ok {
+		c.t.Fatal("the adapter closed its output")
+	}
+	return msg
+}
+
+// request sends a request and returns the body of its response,
+// the events received in between are kept
+func (c *adapterClient) request(command string, args interface{}) map[string]interface{} {
+	c.t.Helper()
+	msg := c.send(command, args)
+	if msg["success"] != true {
+		c.t.Fatalf("%s failed: %v", command, msg["message"])
+	}
+	body, _ := msg["body"].(map[string]interface{})
+	return body
+}
+
+// send sends a request and returns its response,
+// the events received in between are kept
+func (c *adapterClient) send(command string, args interface{}) map[string]interface{} {
+	c.t.Helper()
+	c.seq++
+	content, err := json.Marshal(map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(content), content); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		msg := c.receive()
+		if msg["type"] == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+		if msg["request_seq"] != float64(c.seq) || msg["command"] != command {
+			c.t.Fatalf("got %v, want the response to %s", msg, command)
+		}
+		return msg
+	}
+}
+
+// wait returns the body of the next event with the given name
+func (c *adapterClient) wait(name string) map[string]interface{} {
+	c.t.Helper()
+	for {
+		var msg map[string]interface{}
+		if len(c.events) > 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.receive()
+		}
+		if msg["type"] == "event" && msg["event"] == name {
+			body, _ := msg["body"].(map[string]interface{})
+			return body
+		}
+		if msg["event"] != "output" {
+			c.t.Fatalf("got %v, want a %s event", msg, name)
+		}
+	}
+}
+
+// where returns the function and line of the innermost frame
+func (c *adapterClient) where() string {
+	c.t.Helper()
+	frames := c.request("stackTrace", map[string]interface{}{"threadId": 1})["stackFrames"].([]interface{})
+	top := frames[0].(map[string]interface{})
+	return fmt.Sprintf("%s:%v", top["name"], top["line"])
+}
+
+const debugged = `var total = 0;
+fun add(a, b) {
+  var sum = a + b;
+  return sum;
+}
+var numbers = [1, 2];
+for (var i = 0; i < 2; i = i + 1) {
+  total = add(total, numbers[i]);
+}
+print total;
+`
+
+func TestDebugAdapter(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "sum.lox")
+	if err := os.WriteFile(program, []byte(debugged), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := startAdapter(t)
+
+	c.request("initialize", map[string]interface{}{"adapterID": "golox"})
+	c.wait("initialized")
+	c.request("launch", map[string]interface{}{"program": program, "stopOnEntry": true})
+	breakpoints := c.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]interface{}{"path": program},
+		"breakpoints": []interface{}{map[string]interface{}{"line": 3}},
+	})["breakpoints"].([]interface{})
+	if len(breakpoints) != 1 || breakpoints[0].(map[string]interface{})["verified"] != true {
+		t.Errorf("breakpoints: %v", breakpoints)
+	}
+	c.request("configurationDone", nil)
+
+	if stopped := c.wait("stopped"); stopped["reason"] != "entry" {
+		t.Errorf("stopped: %v, want entry", stopped)
+	}
+	if where := c.where(); where != "<script>:1" {
+		t.Errorf("entry at %s", where)
+	}
+
+	c.request("continue", map[string]interface{}{"threadId": 1})
+	if stopped := c.wait("stopped"); stopped["reason"] != "breakpoint" {
+		t.Errorf("stopped: %v, want breakpoint", stopped)
+	}
+	frames := c.request("stackTrace", map[string]interface{}{"threadId": 1})["stackFrames"].([]interface{})
+	var stack []string
+	for _, f := range frames {
+		f := f.(map[string]interface{})
+		stack = append(stack, fmt.Sprintf("%s:%v", f["name"], f["line"]))
+	}
+	if want := []string{"add:3", "<script>:8"}; !reflect.DeepEqual(stack, want) {
+		t.Errorf("stack: %v, want %v", stack, want)
+	}
+
+	t.Run("variables", func(t *testing.T) {
+		scopes := c.request("scopes", map[string]interface{}{"frameId": 2})["scopes"].([]interface{})
+		var names []string
+		for _, scope := range scopes {
+			names = append(names, scope.(map[string]interface{})["name"].(string))
+		}
+		if want := []string{"Locals", "Enclosing", "Globals"}; !reflect.DeepEqual(names, want) {
+			t.Fatalf("scopes: %v, want %v", names, want)
+		}
+		variables := func(reference interface{}) map[string]interface{} {
+			vars := c.request("variables", map[string]interface{}{"variablesReference": reference})["variables"].([]interface{})
+			byName := map[string]interface{}{}
+			for _, v := range vars {
+				v := v.(map[string]interface{})
+				byName[v["name"].(string)] = v
+			}
+			return byName
+		}
+		if i := variables(scopes[1].(map[string]interface{})["variablesReference"])["i"]; i.(map[string]interface{})["value"] != "0" {
+			t.Errorf("i: %v", i)
+		}
+		globals := variables(scopes[2].(map[string]interface{})["variablesReference"])
+		list := globals["numbers"].(map[string]interface{})
+		if list["value"] != "[1, 2]" {
+			t.Errorf("numbers: %v", list)
+		}
+		if second := variables(list["variablesReference"])["[1]"]; second.(map[string]interface{})["value"] != "2" {
+			t.Errorf("numbers[1]: %v", second)
+		}
+	})
+
+	t.Run("evaluate", func(t *testing.T) {
+		for _, test := range []struct {
+			expr  string
+			frame int
+			want  string
+		}{
+			{"a + b * 10", 1, "10"},
+			{"numbers[i] + total", 2, "1"},
+			{"b = 5", 1, "5"},
+		} {
+			body := c.request("evaluate", map[string]interface{}{"expression": test.expr, "frameId": test.frame})
+			if body["result"] != test.want {
+				t.Errorf("%s: got %v, want %s", test.expr, body["result"], test.want)
+			}
+		}
+	})
+
+	c.request("next", map[string]interface{}{"threadId": 1})
+	c.wait("stopped")
+	if where := c.where(); where != "add:4" {
+		t.Errorf("next: at %s, want add:4", where)
+	}
+	c.request("stepOut", map[string]interface{}{"threadId": 1})
+	c.wait("stopped")
+	// the second iteration of the loop
+	if where := c.where(); where != "<script>:8" {
+		t.Errorf("stepOut: at %s, want <script>:8", where)
+	}
+	c.request("stepIn", map[string]interface{}{"threadId": 1})
+	c.wait("stopped")
+	if where := c.where(); where != "add:3" {
+		t.Errorf("stepIn: at %s, want add:3", where)
+	}
+
+	c.request("setBreakpoints", map[string]interface{}{"source": map[string]interface{}{"path": program}, "breakpoints": []interface{}{}})
+	c.request("continue", map[string]interface{}{"threadId": 1})
+	// b was set to 5 while paused in the first call
+	if output := c.wait("output"); output["output"] != "7\n" {
+		t.Errorf("output: %v", output)
+	}
+	if exited := c.wait("exited"); exited["exitCode"] != float64(0) {
+		t.Errorf("exited: %v", exited)
+	}
+	c.wait("terminated")
+	c.request("disconnect", nil)
+	if err := <-c.done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDebugAdapterTerminate(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "loop.lox")
+	if err := os.WriteFile(program, []byte("while (true) {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := startAdapter(t)
+	c.request("initialize", nil)
+	c.wait("initialized")
+	c.request("launch", map[string]interface{}{"program": program})
+	c.request("configurationDone", nil)
+	// the script runs once
+	for _, command := range []string{"configurationDone", "launch"} {
+		if response := c.send(command, map[string]interface{}{"program": program}); response["success"] == true {
+			t.Errorf("%s while the script runs succeeded", command)
+		}
+	}
+
+	c.request("pause", map[string]interface{}{"threadId": 1})
+	if stopped := c.wait("stopped"); stopped["reason"] != "pause" {
+		t.Errorf("stopped: %v, want pause", stopped)
+	}
+	c.request("terminate", nil)
+	c.wait("exited")
+	c.wait("terminated")
+	c.request("disconnect", nil)
+	if err := <-c.done; err != nil && !strings.Contains(err.Error(), "closed") {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,139 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/lox"
+)
+
+// code returns the code of the single diagnostic of err
+func code(t *testing.T, err error) diag.Code {
+	t.Helper()
+	diags, ok := err.(diag.List)
+	if !ok || len(diags) != 1 {
+		t.Fatalf("got %v, want a diagnostic", err)
+	}
+	return diags[0].Code
+}
+
+func TestEmbedBudgets(t *testing.T) {
+	vm := lox.New()
+	var stdout bytes.Buffer
+	vm.SetOutput(&stdout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := vm.EvalContext(ctx, spinning); code(t, err) != diag.Cancelled {
+		t.Errorf("got %v, want a cancelled error", err)
+	}
+
+	vm.SetBudget(1000, 0)
+	if _, err := vm.Eval(spinning); code(t, err) != diag.BudgetExceeded {
+		t.Errorf("got %v, want an exceeded budget", err)
+	}
+	// the budget is for each evaluation
+	if v, err := vm.Eval("var n = 0; while (n < 500) n = n + 1; n;"); err != nil || v != 500.0 {
+		t.Errorf("got %v, %v, want 500", v, err)
+	}
+	vm.SetBudget(0, 100)
+	if _, err := vm.Eval(allocating); code(t, err) != diag.BudgetExceeded {
+		t.Errorf("got %v, want an exceeded budget", err)
+	}
+
+	if bytes.Contains(stdout.Bytes(), []byte("caught")) || bytes.Contains(stdout.Bytes(), []byte("after")) {
+		t.Errorf("a script went on after being stopped:\n%s", stdout.String())
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	vm := lox.New()
+	var stdout bytes.Buffer
+	vm.SetOutput(&stdout)
+
+	if v, err := vm.Eval("var greeting = \"hello\"; 1 + 2;"); err != nil || v != 3.0 {
+		t.Errorf("Eval: got %v, %v, want 3", v, err)
+	}
+	if v, ok := vm.GetGlobal("greeting"); !ok || v != "hello" {
+		t.Errorf("GetGlobal: got %v, %v, want hello", v, ok)
+	}
+	if _, ok := vm.GetGlobal("undefined"); ok {
+		t.Errorf("GetGlobal: undefined is defined")
+	}
+	if _, err := vm.Eval("print 1 +;"); code(t, err) != diag.ExpectedExpression {
+		t.Errorf("got %v, want a syntax error", err)
+	}
+
+	// the Go numbers are converted to Lox numbers
+	if err := vm.SetGlobal("n", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.SetGlobal("x", float32(0.5)); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := vm.Eval("n * 2 + x;"); err != nil || v != 6.5 {
+		t.Errorf("SetGlobal: got %v, %v, want 6.5", v, err)
+	}
+
+	vm.DefineFunc("half", 1, func(args []lox.Value) (lox.Value, error) {
+		n, ok := args[0].(float64)
+		if !ok {
+			return nil, errors.New("half takes a number.")
+		}
+		return int(n) / 2, nil
+	})
+	if v, err := vm.Eval("half(7);"); err != nil || v != 3.0 {
+		t.Errorf("DefineFunc: got %v, %v, want 3", v, err)
+	}
+	// the errors of the host are caught by the scripts
+	if v, err := vm.Eval(`var message; try { half("7"); } catch (e) { message = e.message; } message;`); err != nil || v != "half takes a number." {
+		t.Errorf("got %v, %v, want the error of half", v, err)
+	}
+	if _, err := vm.Eval(`half("7");`); code(t, err) != diag.NativeError {
+		t.Errorf("got %v, want a native error", err)
+	}
+
+	// the Go lists and maps are converted, the other Go values are rejected
+	if err := vm.SetGlobal("l", []lox.Value{1, "a", map[string]lox.Value{"b": int8(2), "a": []lox.Value{}}}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := vm.Eval("str(l);"); err != nil || v != "[1, a, {a: [], b: 2}]" {
+		t.Errorf("SetGlobal: got %v, %v, want the converted list", v, err)
+	}
+	if err := vm.SetGlobal("ints", []int{1, 2}); code(t, err) != diag.NativeError {
+		t.Errorf("got %v, want a native error", err)
+	}
+	if _, ok := vm.GetGlobal("ints"); ok {
+		t.Errorf("SetGlobal: an unsupported value was defined")
+	}
+	vm.DefineFunc("ints", 0, func(args []lox.Value) (lox.Value, error) {
+		return []int{1, 2}, nil
+	})
+	if _, err := vm.Eval("ints() == ints();"); code(t, err) != diag.NativeError {
+		t.Errorf("got %v, want a native error", err)
+	}
+
+	// the functions of the scripts are called from Go
+	if _, err := vm.Eval("fun add(a, b) { print a + b; return a + b; }"); err != nil {
+		t.Fatal(err)
+	}
+	add, _ := vm.GetGlobal("add")
+	if v, err := vm.Call(add, 1, 2.5); err != nil || v != 3.5 {
+		t.Errorf("Call: got %v, %v, want 3.5", v, err)
+	}
+	if _, err := vm.Call(add, 1); code(t, err) != diag.ArityMismatch {
+		t.Errorf("got %v, want an arity mismatch", err)
+	}
+	if _, err := vm.Call(add, "a", 1); code(t, err) != diag.TypeError {
+		t.Errorf("got %v, want a type error", err)
+	}
+	if _, err := vm.Call(add, []int{1}, 2); code(t, err) != diag.NativeError {
+		t.Errorf("got %v, want a native error", err)
+	}
+	if got := stdout.String(); got != "3.5\n" {
+		t.Errorf("printed %q, want 3.5", got)
+	}
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	formatter "github.com/taki-mekhalfa/golox/format"
+	"github.com/taki-mekhalfa/golox/scanner"
+)
+
+// TestFormat formats the scripts without static errors and checks
+// that formatting is idempotent, keeps the comments in order and that
+// the formatted scripts still print what their annotations expect
+func TestFormat(t *testing.T) {
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || filepath.Ext(path) != ".lox" {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		src := string(b)
+		if len(parseExpectations(t, src).errors) > 0 {
+			return nil
+		}
+
+		t.Run(strings.TrimSuffix(path, ".lox"), func(t *testing.T) {
+			formatted, err := formatter.Source(path, src)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			again, err := formatter.Source(path, formatted)
+			if err != nil {
+				t.Fatalf("unexpected error formatting the formatted script: %v", err)
+			}
+			if again != formatted {
+				t.Errorf("formatting is not idempotent:\n%s\nthen:\n%s", formatted, again)
+			}
+			if got, want := comments(t, formatted), comments(t, src); strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("got the comments %q, want %q", got, want)
+			}
+			if !strings.Contains(filepath.ToSlash(path), "modules/") {
+				check(t, path, formatted, treeWalk)
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestFormatComments checks that the comments stay next to their code
+func TestFormatComments(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{
+			"if (true) {\n  print 1;\n} // before else\nelse {\n  print 2;\n}\n",
+			"if (true) {\n  print 1;\n} // before else\nelse {\n  print 2;\n}\n",
+		},
+		{
+			"if (true) {\n  print 1;\n}\n// before else\nelse print 2;\n",
+			"if (true) {\n  print 1;\n}\n// before else\nelse\n  print 2;\n",
+		},
+		{
+			"if (true) print 1;\n// before else\nelse print 2;\n",
+			"if (true)\n  print 1;\n// before else\nelse\n  print 2;\n",
+		},
+		{
+			"if (true) { print 1; } /* before else */ else { print 2; }\n",
+			"if (true) {\n  print 1;\n} /* before else */ else {\n  print 2;\n}\n",
+		},
+		{
+			"fun f(a, /* the b */ b) {\n  return a;\n}\n",
+			"fun f(a, /* the b */ b) {\n  return a;\n}\n",
+		},
+		{
+			"print f(1, // one\n  2); // two\n",
+			"print f(1, // one\n  2); // two\n",
+		},
+		{
+			"var x = [1,   /* a */ 2];\nprint x /* after x */ + 1;\n",
+			"var x = [1, /* a */ 2];\nprint x /* after x */ + 1;\n",
+		},
+		{
+			"var a = 1 // one\n; // two\n",
+			"var a = 1; // one\n// two\n",
+		},
+	}
+	for _, test := range tests {
+		got, err := formatter.Source("", test.src)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.src, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q: got\n%s\nwant\n%s", test.src, got, test.want)
+		}
+		if again, _ := formatter.Source("", got); again != got {
+			t.Errorf("%q: formatting is not idempotent:\n%s\nthen:\n%s", test.src, got, again)
+		}
+	}
+}
+
+// comments returns the texts of the comments of src in order
+func comments(t *testing.T, src string) []string {
+	scanner := scanner.Scanner{}
+	scanner.Init(src)
+	if err := scanner.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, token := range scanner.Tokens() {
+		for _, c := range token.Comments {
+			texts = append(texts, strings.TrimRight(c.Text, "\r"))
+		}
+	}
+	return texts
+}
@@ -0,0 +1,48 @@
+package test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/taki-mekhalfa/golox/diag"
+)
+
+// TestImportDiagnostics checks that the diagnostics of a module
+// that can't be loaded are reported after the import error
+func TestImportDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	module := filepath.Join(dir, "broken.lox")
+	if err := os.WriteFile(module, []byte("var a = 1;\nprint a +;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "main.lox")
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := backend.run(script, "print 1;\nimport \"broken.lox\";\n", &stdout)
+			diags, ok := err.(diag.List)
+			if !ok || len(diags) != 2 {
+				t.Fatalf("got %v, want the import error and the error of the module", err)
+			}
+			if d := diags[0]; d.Code != diag.ImportError || d.File != script || d.Line != 2 || d.Message != "Could not load module 'broken.lox'." {
+				t.Errorf("import error: %+v", d)
+			}
+			if d := diags[1]; d.Code != diag.ExpectedExpression || d.File != module || d.Line != 2 || d.Column != 10 {
+				t.Errorf("module error: %+v", d)
+			}
+
+			// the import error is caught like the other runtime errors
+			stdout.Reset()
+			src := "try {\n  import \"broken.lox\";\n  print broken;\n} catch (e) {\n  print e.message;\n}\n"
+			if err := backend.run(script, src, &stdout); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := stdout.String(), "Could not load module 'broken.lox'.\n"; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// Package test runs the Lox scripts of this directory with the tree-walk
+// interpreter and the bytecode virtual machine and checks what they print
+// against the annotations of the scripts, in the format of the test suite
+// of Crafting Interpreters:
+//
+//	print 1 + 2;     // expect: 3
+//	print nil - 1;   // expect runtime error: Operands must be both numbers.
+//	var a = ;        // Error at ';': Expected an expression.
+//	// [line 3] Error at end: Expected '}' after block.
+package test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/interpreter"
+	"github.com/taki-mekhalfa/golox/resolver"
+	"github.com/taki-mekhalfa/golox/vm"
+)
+
+var (
+	expectOutput       = regexp.MustCompile(`// expect: ?(.*)`)
+	expectRuntimeError = regexp.MustCompile(`// expect runtime error: (.+)`)
+	expectError        = regexp.MustCompile(`// (Error.*)`)
+	expectErrorAtLine  = regexp.MustCompile(`// \[line (\d+)\] (Error.*)`)
+)
+
+// expectations are what running a script should print
+type expectations struct {
+	output []string
+	// errors are the scanning, parsing and resolving errors
+	errors []string
+	// runtimeError is the message of the error the script
+	// should stop at and runtimeLine its line
+	runtimeError string
+	runtimeLine  int
+}
+
+func parseExpectations(t *testing.T, src string) expectations {
+	var e expectations
+	for i, line := range strings.Split(src, "\n") {
+		n := i + 1
+		if m := expectOutput.FindStringSubmatch(line); m != nil {
+			e.output = append(e.output, m[1])
+		} else if m := expectRuntimeError.FindStringSubmatch(line); m != nil {
+			if e.runtimeError != "" {
+				t.Fatalf("line %d: a script can expect only one runtime error", n)
+			}
+			e.runtimeError, e.runtimeLine = m[1], n
+		} else if m := expectErrorAtLine.FindStringSubmatch(line); m != nil {
+			e.errors = append(e.errors, fmt.Sprintf("[line %s] %s", m[1], m[2]))
+		} else if m := expectError.FindStringSubmatch(line); m != nil {
+			e.errors = append(e.errors, fmt.Sprintf("[line %d] %s", n, m[1]))
+		}
+	}
+	return e
+}
+
+// format formats a static error the way the annotations of the scripts do
+func format(d diag.Diagnostic, src string) string {
+	switch {
+	case d.Phase == diag.Scan:
+		return fmt.Sprintf("[line %d] Error: %s", d.Line, d.Message)
+	case d.Offset >= len(src):
+		return fmt.Sprintf("[line %d] Error at end: %s", d.Line, d.Message)
+	default:
+		return fmt.Sprintf("[line %d] Error at '%s': %s", d.Line, src[d.Offset:d.End], d.Message)
+	}
+}
+
+// backend runs a script and returns the error it stopped at,
+// the setups change the options of the run
+type backend func(file, src string, stdout *bytes.Buffer, setups ...setup) error
+
+// options are the settings of a run shared by the backends,
+// the zero values are the defaults of the backends
+type options struct {
+	ctx            context.Context
+	stdin          io.Reader
+	maxDepth       int
+	maxSteps       int
+	maxAllocations int
+}
+
+type setup func(o *options)
+
+func optionsOf(setups []setup) options {
+	o := options{ctx: context.Background()}
+	for _, setup := range setups {
+		setup(&o)
+	}
+	return o
+}
+
+// backends are the tree-walk interpreter and the virtual machine
+var backends = []struct {
+	name string
+	run  backend
+}{
+	{"tree-walk", treeWalk},
+	{"vm", virtualMachine},
+}
+
+func treeWalk(file, src string, stdout *bytes.Buffer, setups ...setup) error {
+	o := optionsOf(setups)
+	var i interpreter.Interpreter
+	i.Init()
+	i.File, i.Stdout, i.Stdin = file, stdout, o.stdin
+	i.MaxDepth, i.MaxSteps, i.MaxAllocations = o.maxDepth, o.maxSteps, o.maxAllocations
+	i.Load = func(path, src string) ([]ast.Stmt, error) {
+		return resolver.Compile(path, src, &i)
+	}
+	stmts, err := resolver.Compile(file, src, &i)
+	if err != nil {
+		return err
+	}
+	return i.InterpretContext(o.ctx, stmts)
+}
+
+func virtualMachine(file, src string, stdout *bytes.Buffer, setups ...setup) error {
+	o := optionsOf(setups)
+	var m vm.VM
+	m.Init()
+	m.File, m.Stdout, m.Stdin = file, stdout, o.stdin
+	m.MaxDepth, m.MaxSteps, m.MaxAllocations = o.maxDepth, o.maxSteps, o.maxAllocations
+	m.Load = func(path, src string) ([]ast.Stmt, error) {
+		return resolver.Compile(path, src, nil)
+	}
+	stmts, err := resolver.Compile(file, src, nil)
+	if err != nil {
+		return err
+	}
+	return m.InterpretContext(o.ctx, stmts)
+}
+
+func TestScripts(t *testing.T) {
+	var scripts []string
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		// the modules imported by the scripts are not run on their own
+		if d.IsDir() && d.Name() == "modules" {
+			return filepath.SkipDir
+		}
+		if filepath.Ext(path) == ".lox" {
+			scripts = append(scripts, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, script := range scripts {
+		b, err := os.ReadFile(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src := string(b)
+		for _, backend := range backends {
+			t.Run(strings.TrimSuffix(script, ".lox")+"/"+backend.name, func(t *testing.T) {
+				check(t, script, src, backend.run)
+			})
+		}
+	}
+}
+
+// check runs the script and compares what it printed with its annotations
+func check(t *testing.T, script, src string, run backend) {
+	want := parseExpectations(t, src)
+
+	var stdout bytes.Buffer
+	err := run(script, src, &stdout)
+
+	var diags diag.List
+	if err != nil {
+		var ok bool
+		if diags, ok = err.(diag.List); !ok {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	var errors []string
+	var runtimeError string
+	var runtimeLine int
+	for _, d := range diags {
+		if d.Phase == diag.Runtime {
+			runtimeError, runtimeLine = d.Message, d.Line
+			continue
+		}
+		errors = append(errors, format(d, src))
+	}
+
+	// the resolver reports unused variables when their scope ends,
+	// after the errors of the statements that follow them
+	sort.Strings(errors)
+	sort.Strings(want.errors)
+	if got := strings.Join(errors, "\n"); got != strings.Join(want.errors, "\n") {
+		t.Errorf("errors:\n%s\nwant:\n%s", got, strings.Join(want.errors, "\n"))
+	}
+	if runtimeError != want.runtimeError || runtimeLine != want.runtimeLine {
+		t.Errorf("runtime error: %s\nwant: %s",
+			describe(runtimeError, runtimeLine), describe(want.runtimeError, want.runtimeLine))
+	}
+
+	output := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if stdout.Len() == 0 {
+		output = nil
+	}
+	if got := strings.Join(output, "\n"); got != strings.Join(want.output, "\n") {
+		t.Errorf("output:\n%s\nwant:\n%s", got, strings.Join(want.output, "\n"))
+	}
+}
+
+func describe(msg string, line int) string {
+	if msg == "" {
+		return "none"
+	}
+	return "[line " + strconv.Itoa(line) + "] " + msg
+}
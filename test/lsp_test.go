@@ -0,0 +1,334 @@
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/taki-mekhalfa/golox/lsp"
+)
+
+// client talks to a language server running in process
+type client struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *textproto.Reader
+	nextID int
+	// notifications are the notifications received while
+	// waiting for the response to a request
+	notifications []map[string]interface{}
+	done          chan error
+}
+
+func startServer(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{t: t, in: inW, out: textproto.NewReader(bufio.NewReader(outR)), done: make(chan error, 1)}
+	go func() {
+		err := lsp.Serve(inR, outW)
+		outW.Close()
+		c.done <- err
+	}()
+	return c
+}
+
+func (c *client) send(msg map[string]interface{}) {
+	msg["jsonrpc"] = "2.0"
+	content, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(content), content); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) receive() map[string]interface{} {
+	header, err := c.out.ReadMIMEHeader()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(c.out.R, content); err != nil {
+		c.t.Fatal(err)
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(content, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// request sends a request and returns its response
+func (c *client) request(method string, params interface{}) map[string]interface{} {
+	c.nextID++
+	c.send(map[string]interface{}{"id": c.nextID, "method": method, "params": params})
+	for {
+		msg := c.receive()
+		if _, ok := msg["id"]; !ok {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+		if msg["id"] != float64(c.nextID) {
+			c.t.Fatalf("response to request %v, want %d", msg["id"], c.nextID)
+		}
+		return msg
+	}
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.send(map[string]interface{}{"method": method, "params": params})
+}
+
+// diagnostics waits for the diagnostics published after a notification
+func (c *client) diagnostics() []interface{} {
+	msg := c.receive()
+	if msg["method"] != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("got %v, want diagnostics", msg)
+	}
+	return msg["params"].(map[string]interface{})["diagnostics"].([]interface{})
+}
+
+// decode converts a JSON value to the Go value of want's type
+func decode(t *testing.T, v interface{}, want interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reflect.New(reflect.TypeOf(want))
+	if err := json.Unmarshal(b, got.Interface()); err != nil {
+		t.Fatal(err)
+	}
+	return got.Elem().Interface()
+}
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type span struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+func at(line, character int) position { return position{line, character} }
+
+func spanOf(line, start, end int) span { return span{at(line, start), at(line, end)} }
+
+func positionParams(line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     at(line, character),
+	}
+}
+
+const uri = "file:///shapes.lox"
+
+const source = `var total = 0;
+fun area(width, height) {
+  var result = width * height;
+  total = total + result;
+  return result;
+}
+class Rect < Shape {
+  init(w) { this.w = w; }
+}
+class Shape {}
+print area(2, 3);
+`
+
+func TestLanguageServer(t *testing.T) {
+	c := startServer(t)
+
+	initialize := c.request("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}})
+	capabilities := initialize["result"].(map[string]interface{})["capabilities"].(map[string]interface{})
+	if capabilities["definitionProvider"] != true || capabilities["renameProvider"] != true {
+		t.Errorf("capabilities: %v", capabilities)
+	}
+	c.notify("initialized", map[string]interface{}{})
+
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "lox", "version": 1, "text": source},
+	})
+	if diags := c.diagnostics(); len(diags) != 0 {
+		t.Errorf("diagnostics: %v", diags)
+	}
+
+	t.Run("definition", func(t *testing.T) {
+		// 'width' in the body of area
+		response := c.request("textDocument/definition", positionParams(2, 16))
+		got := decode(t, response["result"], struct {
+			URI   string `json:"uri"`
+			Range span   `json:"range"`
+		}{})
+		want := struct {
+			URI   string `json:"uri"`
+			Range span   `json:"range"`
+		}{uri, spanOf(1, 9, 14)}
+		if got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		// the superclass of Rect is declared after it
+		response = c.request("textDocument/definition", positionParams(6, 14))
+		if got := decode(t, response["result"], struct{ Range span }{}); got != (struct{ Range span }{spanOf(9, 6, 11)}) {
+			t.Errorf("superclass: got %v", got)
+		}
+
+		// properties are looked up at runtime
+		if response := c.request("textDocument/definition", positionParams(7, 18)); response["result"] != nil {
+			t.Errorf("property: got %v, want null", response["result"])
+		}
+	})
+
+	t.Run("references", func(t *testing.T) {
+		params := positionParams(0, 5)
+		params["context"] = map[string]interface{}{"includeDeclaration": true}
+		response := c.request("textDocument/references", params)
+		got := decode(t, response["result"], []struct{ Range span }{}).([]struct{ Range span })
+		want := []struct{ Range span }{{spanOf(0, 4, 9)}, {spanOf(3, 10, 15)}, {spanOf(3, 2, 7)}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		response := c.request("textDocument/hover", positionParams(10, 7))
+		contents := response["result"].(map[string]interface{})["contents"].(map[string]interface{})
+		if want := "```lox\nfunction area(width, height)\n```"; contents["value"] != want {
+			t.Errorf("got %q, want %q", contents["value"], want)
+		}
+		response = c.request("textDocument/hover", positionParams(2, 25))
+		contents = response["result"].(map[string]interface{})["contents"].(map[string]interface{})
+		if want := "```lox\nparameter height\n```"; contents["value"] != want {
+			t.Errorf("got %q, want %q", contents["value"], want)
+		}
+	})
+
+	t.Run("documentSymbol", func(t *testing.T) {
+		type symbol struct {
+			Name     string
+			Kind     int
+			Children []symbol
+		}
+		response := c.request("textDocument/documentSymbol", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": uri},
+		})
+		got := decode(t, response["result"], []symbol{})
+		want := []symbol{
+			{Name: "area", Kind: 12},
+			{Name: "Rect", Kind: 5, Children: []symbol{{Name: "init", Kind: 6}}},
+			{Name: "Shape", Kind: 5},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		labels := func(line, character int) map[string]bool {
+			response := c.request("textDocument/completion", positionParams(line, character))
+			labels := map[string]bool{}
+			for _, item := range response["result"].([]interface{}) {
+				labels[item.(map[string]interface{})["label"].(string)] = true
+			}
+			return labels
+		}
+		inside := labels(4, 2)
+		for _, name := range []string{"result", "width", "height", "total", "area", "Shape", "while", "clock", "math"} {
+			if !inside[name] {
+				t.Errorf("%s is not completed in the function", name)
+			}
+		}
+		if outside := labels(10, 0); outside["result"] || outside["width"] {
+			t.Errorf("the locals of the function are completed outside of it")
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		params := positionParams(3, 12)
+		params["newName"] = "sum"
+		response := c.request("textDocument/rename", params)
+		type edit struct {
+			Range   span
+			NewText string
+		}
+		got := decode(t, response["result"], struct{ Changes map[string][]edit }{})
+		want := struct{ Changes map[string][]edit }{map[string][]edit{uri: {
+			{spanOf(0, 4, 9), "sum"}, {spanOf(3, 10, 15), "sum"}, {spanOf(3, 2, 7), "sum"},
+		}}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		params["newName"] = "while"
+		if response := c.request("textDocument/rename", params); response["error"] == nil {
+			t.Errorf("renaming to a keyword: got %v, want an error", response["result"])
+		}
+
+		// 'result' and 'width' renamed to names they would capture or shadow
+		for _, rename := range []struct {
+			line, character int
+			name            string
+		}{{2, 6, "total"}, {2, 6, "height"}, {1, 9, "height"}} {
+			params := positionParams(rename.line, rename.character)
+			params["newName"] = rename.name
+			if response := c.request("textDocument/rename", params); response["error"] == nil {
+				t.Errorf("renaming %d:%d to %s: got %v, want an error", rename.line, rename.character, rename.name, response["result"])
+			}
+		}
+		// the parameter of init is in another scope
+		params = positionParams(1, 9)
+		params["newName"] = "w"
+		if response := c.request("textDocument/rename", params); response["error"] != nil {
+			t.Errorf("renaming width to w: %v", response["error"])
+		}
+	})
+
+	t.Run("didChange", func(t *testing.T) {
+		c.notify("textDocument/didChange", map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+			"contentChanges": []interface{}{map[string]interface{}{"text": "var a = ;\n{ var é = 1; }\n"}},
+		})
+		type diagnostic struct {
+			Range    span
+			Severity int
+			Code     string
+			Message  string
+		}
+		got := decode(t, c.diagnostics(), []diagnostic{})
+		want := []diagnostic{{spanOf(0, 8, 9), 1, "P002", "Expected an expression."}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		c.notify("textDocument/didChange", map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": uri, "version": 3},
+			"contentChanges": []interface{}{map[string]interface{}{"text": "{ var é = 1; }\n"}},
+		})
+		// columns are counted in UTF-16 code units
+		got = decode(t, c.diagnostics(), []diagnostic{})
+		want = []diagnostic{{spanOf(0, 6, 7), 1, "R011", "é declared but not used."}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	if response := c.request("shutdown", nil); response["error"] != nil {
+		t.Errorf("shutdown: %v", response["error"])
+	}
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("exit: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/taki-mekhalfa/golox/interpreter"
+	"github.com/taki-mekhalfa/golox/resolver"
+)
+
+const profiled = `fun fib(n) {
+  if (n < 2) return n;
+  return fib(n - 2) + fib(n - 1);
+}
+class Box {
+  init(v) { this.v = [v, "a" + "b"]; }
+  get() { return this.v[0]; }
+}
+var total = 0;
+for (var i = 0; i < 5; i = i + 1) total = total + Box(fib(i)).get();
+print fib(10);
+`
+
+func TestProfiler(t *testing.T) {
+	var i interpreter.Interpreter
+	i.Init()
+	i.File, i.Stdout = "profiled.lox", &bytes.Buffer{}
+	i.Profiler = &interpreter.Profiler{}
+	stmts, err := resolver.Compile(i.File, profiled, &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Interpret(stmts); err != nil {
+		t.Fatal(err)
+	}
+
+	type function struct {
+		name        string
+		line        int
+		calls       int
+		allocations int
+	}
+	want := map[function]bool{
+		// fib(0) to fib(4) make 1+1+3+5+9 calls and fib(10) 177
+		{"fib", 1, 196, 0}: true,
+		// a list and a string per call
+		{"Box.init", 6, 5, 10}: true,
+		{"Box.get", 7, 5, 0}:   true,
+	}
+	functions := i.Profiler.Functions()
+	if len(functions) != len(want) {
+		t.Errorf("got %d functions, want %d", len(functions), len(want))
+	}
+	for j, f := range functions {
+		got := function{f.Name, f.Line, f.Calls, f.Allocations}
+		if !want[got] || f.File != "profiled.lox" {
+			t.Errorf("unexpected profile %+v", f)
+		}
+		if f.Self > f.Total {
+			t.Errorf("%s: self %v greater than total %v", f.Name, f.Self, f.Total)
+		}
+		if j > 0 && f.Self > functions[j-1].Self {
+			t.Errorf("%s is not sorted by self time", f.Name)
+		}
+	}
+
+	var table bytes.Buffer
+	if err := i.Profiler.WriteTable(&table); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(table.String(), "fib (profiled.lox:1)") {
+		t.Errorf("table:\n%s", table.String())
+	}
+
+	var profile bytes.Buffer
+	if err := i.Profiler.WritePprof(&profile); err != nil {
+		t.Fatal(err)
+	}
+	r, err := gzip.NewReader(&profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var strs []string
+	var defaultSampleType int
+	for len(b) > 0 {
+		field, v, n := protobufField(b)
+		if n == 0 {
+			t.Fatal("the pprof profile is malformed")
+		}
+		switch field {
+		case 6:
+			strs = append(strs, string(v))
+		case 14:
+			defaultSampleType = int(varint(v))
+		}
+		b = b[n:]
+	}
+	for _, s := range []string{"wall", "nanoseconds", "alloc_objects", "Box.init", "profiled.lox"} {
+		if !contains(strs, s) {
+			t.Errorf("the pprof profile has no string %q", s)
+		}
+	}
+	if defaultSampleType >= len(strs) || strs[defaultSampleType] != "wall" {
+		t.Errorf("the default sample type is %d, want wall", defaultSampleType)
+	}
+}
+
+// protobufField decodes the field at the start of b, a varint field is
+// returned encoded. n is the size of the field, 0 when b is malformed.
+func protobufField(b []byte) (field int, v []byte, n int) {
+	key, k := binary.Uvarint(b)
+	if k <= 0 {
+		return 0, nil, 0
+	}
+	switch key & 7 {
+	case 0:
+		_, l := binary.Uvarint(b[k:])
+		if l <= 0 {
+			return 0, nil, 0
+		}
+		return int(key >> 3), b[k : k+l], k + l
+	case 2:
+		size, l := binary.Uvarint(b[k:])
+		if l <= 0 || uint64(len(b)-k-l) < size {
+			return 0, nil, 0
+		}
+		return int(key >> 3), b[k+l : k+l+int(size)], k + l + int(size)
+	}
+	return 0, nil, 0
+}
+
+func varint(b []byte) uint64 {
+	v, _ := binary.Uvarint(b)
+	return v
+}
+
+func contains(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
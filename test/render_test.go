@@ -0,0 +1,75 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/taki-mekhalfa/golox/diag"
+)
+
+// TestRender checks that the columns count characters and that
+// the caret lines up under them with tabs and multi-byte characters
+func TestRender(t *testing.T) {
+	const typeError = "Runtime Error: Operands must be both numbers or both strings."
+	tests := []struct {
+		name, src, want string
+	}{
+		{
+			"multi-byte",
+			"var s = \"héllo\"; print s + 1;\n",
+			"1:24: " + typeError + "\n" +
+				" 1 | var s = \"héllo\"; print s + 1;\n" +
+				"   |                        ^~~~~",
+		},
+		{
+			"tabs",
+			"var s = \"héllo\";\tprint s\t+ 1;\n",
+			"1:24: " + typeError + "\n" +
+				" 1 | var s = \"héllo\";\tprint s\t+ 1;\n" +
+				"   |                 \t      ^~~~~",
+		},
+		{
+			// a range spanning several lines is underlined up to the end of its first line
+			"multi-line",
+			"print \"é\" +\n  1;\n",
+			"1:7: " + typeError + "\n" +
+				" 1 | print \"é\" +\n" +
+				"   |       ^~~~~",
+		},
+		{
+			"scanner",
+			"print \"ü\tx\" @;\n",
+			"1:13: Syntax Error: Unexpected character.\n" +
+				" 1 | print \"ü\tx\" @;\n" +
+				"   |         \t   ^",
+		},
+	}
+	for _, backend := range backends {
+		for _, test := range tests {
+			t.Run(test.name+"/"+backend.name, func(t *testing.T) {
+				var stdout bytes.Buffer
+				err := backend.run("", test.src, &stdout)
+				diags, ok := err.(diag.List)
+				if !ok || len(diags) != 1 {
+					t.Fatalf("got %v, want a diagnostic", err)
+				}
+				if got := diags[0].Render(test.src); got != test.want {
+					t.Errorf("got\n%s\nwant\n%s", got, test.want)
+				}
+			})
+		}
+	}
+
+	// the source line is left out when the column is unknown or past the line
+	for _, test := range []struct {
+		d    diag.Diagnostic
+		want string
+	}{
+		{diag.Diagnostic{Phase: diag.Runtime, Line: 1, Message: "Stopped."}, "1: Runtime Error: Stopped."},
+		{diag.Diagnostic{Phase: diag.Runtime, Line: 1, Column: 4, Message: "Stopped."}, "1:4: Runtime Error: Stopped."},
+	} {
+		if got := test.d.Render("é\n"); got != test.want {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+}
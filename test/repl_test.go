@@ -0,0 +1,90 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/printer"
+	"github.com/taki-mekhalfa/golox/repl"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		code       string
+		incomplete bool
+	}{
+		{"print 1;", false},
+		{"print 1", false},
+		{"fun f() {", true},
+		{"fun f() {\n  return [1,\n", true},
+		{"f(1, (2)", true},
+		{"print \"a", true},
+		{"/* a", true},
+		{"print 1; // {", false},
+		{"print \"{\";", false},
+		// the extra closing brackets are reported when the entry is run
+		{"}", false},
+		{"print 1 @", false},
+	}
+	for _, test := range tests {
+		if got := repl.Incomplete(test.code); got != test.incomplete {
+			t.Errorf("Incomplete(%q) = %v, want %v", test.code, got, test.incomplete)
+		}
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		code string
+		// the statements printed by the pretty printer
+		want []string
+	}{
+		{"print 1;", []string{"PRINT 1"}},
+		{"1 + 2", []string{"(+ 1 2)"}},
+		{"var a = 1", []string{"var a = 1"}},
+		// the semicolon goes before the comments
+		{"1 + 2 // c", []string{"(+ 1 2)"}},
+		{"1 + 2 /* c */", []string{"(+ 1 2)"}},
+		{"{ print 1; }", []string{"{\nPRINT 1\n}"}},
+		{"print 1; 2", []string{"PRINT 1", "2"}},
+		{"\"a\nb\"", []string{"a\nb"}},
+		// a lambda and a map are expressions
+		{"fun (a) { return a; }", nil},
+		{"{\"a\": 1}[\"a\"]", []string{"{a: 1}[a]"}},
+		{"// only a comment", []string{}},
+	}
+	for _, test := range tests {
+		stmts, err := repl.Parse(test.code)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.code, err)
+			continue
+		}
+		if test.want == nil {
+			if len(stmts) != 1 {
+				t.Errorf("Parse(%q): got %d statements, want 1", test.code, len(stmts))
+			} else if es, ok := stmts[0].(*ast.ExprStmt); !ok {
+				t.Errorf("Parse(%q): got %T, want an expression", test.code, stmts[0])
+			} else if _, ok := es.Expr.(*ast.Lambda); !ok {
+				t.Errorf("Parse(%q): got %T, want a lambda", test.code, es.Expr)
+			}
+			continue
+		}
+		if len(stmts) != len(test.want) {
+			t.Errorf("Parse(%q): got %d statements, want %d", test.code, len(stmts), len(test.want))
+			continue
+		}
+		for i, stmt := range stmts {
+			if got := (printer.PrettyPrinter{}).PrintStmt(stmt); got != test.want[i] {
+				t.Errorf("Parse(%q): got %q, want %q", test.code, got, test.want[i])
+			}
+		}
+	}
+
+	// the errors are those of the entry as typed
+	_, err := repl.Parse("print 1 +")
+	diags, ok := err.(diag.List)
+	if !ok || len(diags) != 1 || diags[0].Code != diag.ExpectedExpression || diags[0].Column != 10 {
+		t.Errorf("got %v, want an expected expression at 1:10", err)
+	}
+}
@@ -0,0 +1,41 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/taki-mekhalfa/golox/diag"
+)
+
+const recursing = `fun recurse(n) {
+  return recurse(n + 1);
+}
+recurse(0);
+`
+
+// TestMaxDepth checks that the calls nested deeper than MaxDepth raise a
+// stack overflow and that the trace of the recursion is collapsed
+func TestMaxDepth(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := backend.run("recursing.lox", recursing, &stdout, func(o *options) { o.maxDepth = 50 })
+			diags, ok := err.(diag.List)
+			if !ok || len(diags) != 1 || diags[0].Code != diag.StackOverflow || diags[0].Line != 2 {
+				t.Fatalf("got %v, want a stack overflow at line 2", err)
+			}
+			d := diags[0]
+			// the calls of recurse and the script
+			if len(d.Trace) != 51 {
+				t.Errorf("got %d frames, want 51", len(d.Trace))
+			}
+			rendered := d.Render(recursing)
+			for _, want := range []string{"Stack overflow.", "at recurse() line 2", "... repeated 49 more times", "at <script> line 4"} {
+				if !strings.Contains(rendered, want) {
+					t.Errorf("the trace has no %q:\n%s", want, rendered)
+				}
+			}
+		})
+	}
+}
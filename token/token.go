@@ -6,8 +6,59 @@ type Token struct {
 	Type   Type
 	Lexeme string
 	Line   int
+	// Column is the 1-based column of the token's first
+	// character in its line, counted in code points
+	Column int
+	// Offset is the byte offset of the token in the source
+	Offset int
+	// File is the path of the scanned source, empty for the prompt
+	File string
+	// Literal is the value of a string token, its
+	// lexeme without the quotes and escape sequences
+	Literal string
+	// Comments are the comments between the
+	// previous token and this one, in order
+	Comments []Comment
+}
+
+// Comment is a comment of the source, the scanner
+// keeps them as trivia of the token following them
+type Comment struct {
+	// Text is the comment with its delimiters
+	Text string
+	Span Span
 }
 
 func (t Token) String() string {
 	return fmt.Sprintf("[%s:%s]", t.Type, t.Lexeme)
 }
+
+// Span is a range of source code starting at Line and Column
+type Span struct {
+	File   string
+	Line   int
+	Column int
+	// Offset and End are the byte offsets of
+	// the start and the end of the range
+	Offset int
+	End    int
+}
+
+// SpanOf returns the span of the token's lexeme
+func SpanOf(t Token) Span {
+	return Span{
+		File:   t.File,
+		Line:   t.Line,
+		Column: t.Column,
+		Offset: t.Offset,
+		End:    t.Offset + len(t.Lexeme),
+	}
+}
+
+// To returns the span from the start of s to the end of end
+func (s Span) To(end Span) Span {
+	if end.End > s.End {
+		s.End = end.End
+	}
+	return s
+}
@@ -12,46 +12,56 @@ func _() {
 	_ = x[RIGHT_PAREN-1]
 	_ = x[LEFT_BRACE-2]
 	_ = x[RIGHT_BRACE-3]
-	_ = x[COMMA-4]
-	_ = x[DOT-5]
-	_ = x[MINUS-6]
-	_ = x[PLUS-7]
-	_ = x[SEMICOLON-8]
-	_ = x[SLASH-9]
-	_ = x[STAR-10]
-	_ = x[BANG-11]
-	_ = x[BANG_EQUAL-12]
-	_ = x[EQUAL-13]
-	_ = x[EQUAL_EQUAL-14]
-	_ = x[GREATER-15]
-	_ = x[GREATER_EQUAL-16]
-	_ = x[LESS-17]
-	_ = x[LESS_EQUAL-18]
-	_ = x[IDENTIFIER-19]
-	_ = x[STRING-20]
-	_ = x[NUMBER-21]
-	_ = x[CLASS-22]
-	_ = x[VAR-23]
-	_ = x[PRINT-24]
-	_ = x[NIL-25]
-	_ = x[FUN-26]
-	_ = x[RETURN-27]
-	_ = x[SUPER-28]
-	_ = x[THIS-29]
-	_ = x[AND-30]
-	_ = x[OR-31]
-	_ = x[IF-32]
-	_ = x[ELSE-33]
-	_ = x[FALSE-34]
-	_ = x[TRUE-35]
-	_ = x[FOR-36]
-	_ = x[WHILE-37]
-	_ = x[EOF-38]
+	_ = x[LEFT_BRACKET-4]
+	_ = x[RIGHT_BRACKET-5]
+	_ = x[COMMA-6]
+	_ = x[COLON-7]
+	_ = x[DOT-8]
+	_ = x[MINUS-9]
+	_ = x[PLUS-10]
+	_ = x[SEMICOLON-11]
+	_ = x[SLASH-12]
+	_ = x[STAR-13]
+	_ = x[BANG-14]
+	_ = x[BANG_EQUAL-15]
+	_ = x[EQUAL-16]
+	_ = x[EQUAL_EQUAL-17]
+	_ = x[GREATER-18]
+	_ = x[GREATER_EQUAL-19]
+	_ = x[LESS-20]
+	_ = x[LESS_EQUAL-21]
+	_ = x[IDENTIFIER-22]
+	_ = x[STRING-23]
+	_ = x[NUMBER-24]
+	_ = x[CLASS-25]
+	_ = x[VAR-26]
+	_ = x[PRINT-27]
+	_ = x[NIL-28]
+	_ = x[FUN-29]
+	_ = x[RETURN-30]
+	_ = x[SUPER-31]
+	_ = x[THIS-32]
+	_ = x[AND-33]
+	_ = x[OR-34]
+	_ = x[IF-35]
+	_ = x[ELSE-36]
+	_ = x[FALSE-37]
+	_ = x[TRUE-38]
+	_ = x[FOR-39]
+	_ = x[WHILE-40]
+	_ = x[BREAK-41]
+	_ = x[CONTINUE-42]
+	_ = x[TRY-43]
+	_ = x[CATCH-44]
+	_ = x[FINALLY-45]
+	_ = x[THROW-46]
+	_ = x[IMPORT-47]
+	_ = x[EOF-48]
 }
 
-const _Type_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACECOMMADOTMINUSPLUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALIDENTIFIERSTRINGNUMBERCLASSVARPRINTNILFUNRETURNSUPERTHISANDORIFELSEFALSETRUEFORWHILEEOF"
+const _Type_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACELEFT_BRACKETRIGHT_BRACKETCOMMACOLONDOTMINUSPLUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALIDENTIFIERSTRINGNUMBERCLASSVARPRINTNILFUNRETURNSUPERTHISANDORIFELSEFALSETRUEFORWHILEBREAKCONTINUETRYCATCHFINALLYTHROWIMPORTEOF"
 
-var _Type_index = [...]uint8{0, 10, 21, 31, 42, 47, 50, 55, 59, 68, 73, 77, 81, 91, 96, 107, 114, 127, 131, 141, 151, 157, 163, 168, 171, 176, 179, 182, 188, 193, 197, 200, 202, 204, 208, 213, 217, 220, 225, 228}
+var _Type_index = [...]uint16{0, 10, 21, 31, 42, 54, 67, 72, 77, 80, 85, 89, 98, 103, 107, 111, 121, 126, 137, 144, 157, 161, 171, 181, 187, 193, 198, 201, 206, 209, 212, 218, 223, 227, 230, 232, 234, 238, 243, 247, 250, 255, 260, 268, 271, 276, 283, 288, 294, 297}
 
 func (i Type) String() string {
 	if i < 0 || i >= Type(len(_Type_index)-1) {
@@ -9,7 +9,10 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
 	COMMA
+	COLON
 	DOT
 	MINUS
 	PLUS
@@ -54,25 +57,41 @@ const (
 
 	FOR
 	WHILE
+	BREAK
+	CONTINUE
+
+	TRY
+	CATCH
+	FINALLY
+	THROW
+
+	IMPORT
 
 	EOF
 )
 
 var KeyWords = map[string]Type{
-	"class":  CLASS,
-	"var":    VAR,
-	"print":  PRINT,
-	"nil":    NIL,
-	"fun":    FUN,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"and":    AND,
-	"or":     OR,
-	"if":     IF,
-	"else":   ELSE,
-	"false":  FALSE,
-	"true":   TRUE,
-	"for":    FOR,
-	"while":  WHILE,
+	"class":    CLASS,
+	"var":      VAR,
+	"print":    PRINT,
+	"nil":      NIL,
+	"fun":      FUN,
+	"return":   RETURN,
+	"super":    SUPER,
+	"this":     THIS,
+	"and":      AND,
+	"or":       OR,
+	"if":       IF,
+	"else":     ELSE,
+	"false":    FALSE,
+	"true":     TRUE,
+	"for":      FOR,
+	"while":    WHILE,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
+	"import":   IMPORT,
 }
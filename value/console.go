@@ -0,0 +1,71 @@
+package value
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/taki-mekhalfa/golox/diag"
+)
+
+// Console holds the streams scripts print to and read lines from,
+// nil streams are the standard output and input of the process.
+type Console struct {
+	Stdout io.Writer
+	Stdin  io.Reader
+
+	// in buffers the lines read from Stdin, it
+	// is reset when Stdin is changed by the host
+	in     *bufio.Reader
+	inFrom io.Reader
+}
+
+// Print writes v followed by a new line as the 'print' statement does
+func (c *Console) Print(v interface{}) {
+	fmt.Fprintln(c.stdout(), Stringify(v))
+}
+
+// ReadLine returns the next line read from Stdin without
+// its line terminator, or nil when there are no lines left
+func (c *Console) ReadLine() (interface{}, error) {
+	stdin := c.Stdin
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+	if c.in == nil || c.inFrom != stdin {
+		c.in, c.inFrom = bufio.NewReader(stdin), stdin
+	}
+
+	line, err := c.in.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return nil, nil
+	}
+	if err != nil && err != io.EOF {
+		return nil, diag.Errorf(diag.NativeError, "Could not read a line: %v.", err)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// Natives returns the built-in functions reading from the console
+func (c *Console) Natives() []*Native {
+	return []*Native{
+		// readLine returns the next line of the input, or <nil> at its end
+		{Name: "readLine", Arity: 0, Fn: func(args []interface{}) (interface{}, error) {
+			return c.ReadLine()
+		}},
+		// input prints a prompt and returns the line typed after it
+		{Name: "input", Arity: 1, Fn: func(args []interface{}) (interface{}, error) {
+			fmt.Fprint(c.stdout(), Stringify(args[0]))
+			return c.ReadLine()
+		}},
+	}
+}
+
+func (c *Console) stdout() io.Writer {
+	if c.Stdout == nil {
+		return os.Stdout
+	}
+	return c.Stdout
+}
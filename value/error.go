@@ -0,0 +1,33 @@
+package value
+
+import "github.com/taki-mekhalfa/golox/diag"
+
+// Error is the value caught when recovering from a runtime error,
+// it can also be created and thrown by scripts using the Error built-in.
+type Error struct {
+	Message string
+	// Line is 0 until the error is thrown
+	Line int
+	// Code is the code of the runtime error the
+	// error was recovered from, empty otherwise.
+	Code diag.Code
+	// Causes are the diagnostics of the error it was recovered
+	// from, reported after it when the error is not caught
+	Causes diag.List
+}
+
+// String implements fmt.Stringer
+func (e *Error) String() string {
+	return e.Message
+}
+
+// Property returns the value of the message or line property
+func (e *Error) Property(name string) (interface{}, bool) {
+	switch name {
+	case "message":
+		return e.Message, true
+	case "line":
+		return float64(e.Line), true
+	}
+	return nil, false
+}
@@ -0,0 +1,105 @@
+package value
+
+import (
+	"math"
+	"strings"
+
+	"github.com/taki-mekhalfa/golox/diag"
+)
+
+type List struct {
+	Elements []interface{}
+}
+
+// String implements fmt.Stringer
+func (l *List) String() string {
+	return l.stringify(nil)
+}
+
+func (l *List) stringify(printing []interface{}) string {
+	for _, p := range printing {
+		if p == l {
+			return "[...]"
+		}
+	}
+	printing = append(printing, l)
+	var builder strings.Builder
+	builder.WriteString("[")
+	for i, element := range l.Elements {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(stringify(element, printing))
+	}
+	builder.WriteString("]")
+	return builder.String()
+}
+
+func (l *List) Get(index interface{}) (interface{}, error) {
+	i, err := l.checkIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	return l.Elements[i], nil
+}
+
+func (l *List) Set(index interface{}, value interface{}) error {
+	i, err := l.checkIndex(index)
+	if err != nil {
+		return err
+	}
+	l.Elements[i] = value
+	return nil
+}
+
+// Slice returns a new list holding the elements between start (included)
+// and end (excluded), a <nil> bound defaults to the list's boundary.
+func (l *List) Slice(start, end interface{}) (*List, error) {
+	low, high, err := sliceBounds(start, end, len(l.Elements))
+	if err != nil {
+		return nil, err
+	}
+
+	elements := make([]interface{}, high-low)
+	copy(elements, l.Elements[low:high])
+	return &List{Elements: elements}, nil
+}
+
+// sliceBounds checks the bounds of a slice of a sequence of n elements
+func sliceBounds(start, end interface{}, n int) (low, high int, err error) {
+	low, high = 0, n
+	if start != nil {
+		if low, err = ToInteger(start); err != nil {
+			return 0, 0, err
+		}
+	}
+	if end != nil {
+		if high, err = ToInteger(end); err != nil {
+			return 0, 0, err
+		}
+	}
+	if low < 0 || high > n || low > high {
+		return 0, 0, diag.Errorf(diag.IndexOutOfRange, "Slice bounds out of range.")
+	}
+	return low, high, nil
+}
+
+func (l *List) checkIndex(index interface{}) (int, error) {
+	i, err := ToInteger(index)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 || i >= len(l.Elements) {
+		return 0, diag.Errorf(diag.IndexOutOfRange, "Index out of range.")
+	}
+	return i, nil
+}
+
+// ToInteger converts a Lox number holding an integer value to an int
+func ToInteger(v interface{}) (int, error) {
+	n, ok := v.(float64)
+	if !ok || n != math.Trunc(n) || math.IsInf(n, 0) {
+		return 0, diag.Errorf(diag.TypeError, "Index must be an integer.")
+	}
+	return int(n), nil
+}
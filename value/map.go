@@ -0,0 +1,115 @@
+package value
+
+import (
+	"math"
+	"strings"
+
+	"github.com/taki-mekhalfa/golox/diag"
+)
+
+// Map is the Lox hash map, it remembers the insertion order of its keys.
+// keys are strings, numbers, booleans or <nil> and are compared
+// the same way '==' compares values.
+type Map struct {
+	entries map[interface{}]interface{}
+	keys    []interface{}
+}
+
+func NewMap() *Map {
+	return &Map{entries: make(map[interface{}]interface{})}
+}
+
+// String implements fmt.Stringer
+func (m *Map) String() string {
+	return m.stringify(nil)
+}
+
+func (m *Map) stringify(printing []interface{}) string {
+	for _, p := range printing {
+		if p == m {
+			return "{...}"
+		}
+	}
+	printing = append(printing, m)
+	var builder strings.Builder
+	builder.WriteString("{")
+	for i, key := range m.keys {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(Stringify(key))
+		builder.WriteString(": ")
+		builder.WriteString(stringify(m.entries[key], printing))
+	}
+	builder.WriteString("}")
+	return builder.String()
+}
+
+func (m *Map) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns the keys of the map in insertion order
+func (m *Map) Keys() []interface{} {
+	return append([]interface{}{}, m.keys...)
+}
+
+func (m *Map) Get(key interface{}) (interface{}, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	value, ok := m.entries[key]
+	if !ok {
+		return nil, diag.Errorf(diag.UndefinedKey, "Undefined key '%s'.", Stringify(key))
+	}
+	return value, nil
+}
+
+func (m *Map) Set(key interface{}, value interface{}) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if _, ok := m.entries[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.entries[key] = value
+	return nil
+}
+
+func (m *Map) Has(key interface{}) bool {
+	_, ok := m.entries[key]
+	return ok
+}
+
+// Delete removes the key from the map and reports whether it was present
+func (m *Map) Delete(key interface{}) bool {
+	if _, ok := m.entries[key]; !ok {
+		return false
+	}
+	delete(m.entries, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func isValidKey(key interface{}) bool {
+	switch k := key.(type) {
+	case nil, bool, string:
+		return true
+	case float64:
+		// NaN is never equal to itself so it could never be looked up
+		return !math.IsNaN(k)
+	}
+	return false
+}
+
+func checkKey(key interface{}) error {
+	if !isValidKey(key) {
+		return diag.Errorf(diag.TypeError, "Map keys must be strings, numbers, booleans or nil.")
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package value
+
+import (
+	"math"
+
+	"github.com/taki-mekhalfa/golox/diag"
+)
+
+// Namespace is a built-in object grouping functions and constants
+// under a name, its members are read as properties: math.sqrt(2)
+type Namespace struct {
+	Name    string
+	Members map[string]interface{}
+}
+
+// String implements fmt.Stringer
+func (n *Namespace) String() string {
+	return n.Name + " module"
+}
+
+// Property returns the member called name
+func (n *Namespace) Property(name string) (interface{}, bool) {
+	v, ok := n.Members[name]
+	return v, ok
+}
+
+// Namespaces are the built-in namespaces defined in the global scope
+var Namespaces = []*Namespace{Math}
+
+// Math holds the mathematical functions and constants
+var Math = newNamespace("math", map[string]interface{}{
+	"pi":  math.Pi,
+	"e":   math.E,
+	"inf": math.Inf(1),
+	"nan": math.NaN(),
+},
+	unary("abs", math.Abs),
+	unary("floor", math.Floor),
+	unary("ceil", math.Ceil),
+	// round rounds half away from zero
+	unary("round", math.Round),
+	unary("trunc", math.Trunc),
+	unary("sqrt", math.Sqrt),
+	unary("sin", math.Sin),
+	unary("cos", math.Cos),
+	unary("tan", math.Tan),
+	// log is the natural logarithm
+	unary("log", math.Log),
+	unary("exp", math.Exp),
+	binary("pow", math.Pow),
+	binary("min", math.Min),
+	binary("max", math.Max),
+	// mod returns the remainder of a / b, it has the sign of a
+	&Native{Name: "mod", Arity: 2, Fn: func(args []interface{}) (interface{}, error) {
+		a, b, err := numbers("mod", args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 {
+			return nil, diag.Errorf(diag.DivisionByZero, "Divided by 0.")
+		}
+		return math.Mod(a, b), nil
+	}},
+)
+
+// newNamespace creates a namespace holding the constants and the
+// natives, the natives are named after the namespace when printed
+func newNamespace(name string, constants map[string]interface{}, natives ...*Native) *Namespace {
+	n := &Namespace{Name: name, Members: constants}
+	for _, native := range natives {
+		n.Members[native.Name] = native
+		native.Name = name + "." + native.Name
+	}
+	return n
+}
+
+func unary(name string, fn func(float64) float64) *Native {
+	return &Native{Name: name, Arity: 1, Fn: func(args []interface{}) (interface{}, error) {
+		x, ok := args[0].(float64)
+		if !ok {
+			return nil, diag.Errorf(diag.TypeError, "Argument of math.%s must be a number.", name)
+		}
+		return fn(x), nil
+	}}
+}
+
+func binary(name string, fn func(float64, float64) float64) *Native {
+	return &Native{Name: name, Arity: 2, Fn: func(args []interface{}) (interface{}, error) {
+		a, b, err := numbers(name, args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		return fn(a, b), nil
+	}}
+}
+
+// numbers checks the two arguments of the math function name are numbers
+func numbers(name string, a, b interface{}) (float64, float64, error) {
+	x, xIsNumber := a.(float64)
+	y, yIsNumber := b.(float64)
+	if !xIsNumber || !yIsNumber {
+		return 0, 0, diag.Errorf(diag.TypeError, "Arguments of math.%s must be numbers.", name)
+	}
+	return x, y, nil
+}
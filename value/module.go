@@ -0,0 +1,30 @@
+package value
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// FindModule resolves the path of a module imported by the importer file
+// relative to the importer's directory first, and then to the search path.
+// an empty importer stands for the working directory.
+func FindModule(importer, path string, searchPath []string) (string, bool) {
+	if filepath.IsAbs(path) {
+		return path, true
+	}
+
+	dir := "."
+	if importer != "" {
+		dir = filepath.Dir(importer)
+	}
+	for _, dir := range append([]string{dir}, searchPath...) {
+		abs, err := filepath.Abs(filepath.Join(dir, path))
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(abs); err == nil && !info.IsDir() {
+			return abs, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,129 @@
+package value
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/taki-mekhalfa/golox/diag"
+)
+
+// Native is a built-in function implemented in Go.
+// an error returned by Fn is reported as a runtime error at the call site.
+type Native struct {
+	Name  string
+	Arity int
+	Fn    func(args []interface{}) (interface{}, error)
+	// Reuses is set when the result is held by the arguments, not a new value
+	Reuses bool
+}
+
+// Allocates reports whether result, returned by n, is a new
+// list, map or string charged to the budget of allocations
+func (n *Native) Allocates(result interface{}) bool {
+	switch result.(type) {
+	case string, *List, *Map:
+		return !n.Reuses
+	}
+	return false
+}
+
+// String implements fmt.Stringer
+func (n *Native) String() string {
+	return "<native fn " + n.Name + ">"
+}
+
+// Natives are the built-in functions defined in the global scope
+var Natives = append([]*Native{
+	// clock returns the current time in unix seconds with a fractional part
+	{Name: "clock", Arity: 0, Fn: func(args []interface{}) (interface{}, error) {
+		return float64(time.Now().UnixNano()) / 1e9, nil
+	}},
+	// len returns the number of elements of a list or a map
+	// and the number of characters of a string
+	{Name: "len", Arity: 1, Fn: func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case string:
+			return float64(utf8.RuneCountInString(v)), nil
+		case *List:
+			return float64(len(v.Elements)), nil
+		case *Map:
+			return float64(v.Len()), nil
+		}
+		return nil, diag.Errorf(diag.TypeError, "Can't get the length of %s.", Stringify(args[0]))
+	}},
+	// push appends a value at the end of a list
+	{Name: "push", Arity: 2, Fn: func(args []interface{}) (interface{}, error) {
+		l, ok := args[0].(*List)
+		if !ok {
+			return nil, diag.Errorf(diag.TypeError, "Can only push to a list.")
+		}
+		l.Elements = append(l.Elements, args[1])
+		return nil, nil
+	}},
+	// pop removes the last value of a list and returns it
+	{Name: "pop", Arity: 1, Reuses: true, Fn: func(args []interface{}) (interface{}, error) {
+		l, ok := args[0].(*List)
+		if !ok {
+			return nil, diag.Errorf(diag.TypeError, "Can only pop from a list.")
+		}
+		if len(l.Elements) == 0 {
+			return nil, diag.Errorf(diag.IndexOutOfRange, "Can't pop from an empty list.")
+		}
+		last := l.Elements[len(l.Elements)-1]
+		l.Elements = l.Elements[:len(l.Elements)-1]
+		return last, nil
+	}},
+	// keys returns the keys of a map in insertion order
+	{Name: "keys", Arity: 1, Fn: func(args []interface{}) (interface{}, error) {
+		m, ok := args[0].(*Map)
+		if !ok {
+			return nil, diag.Errorf(diag.TypeError, "Can only get the keys of a map.")
+		}
+		return &List{Elements: m.Keys()}, nil
+	}},
+	// values returns the values of a map in the order of its keys
+	{Name: "values", Arity: 1, Fn: func(args []interface{}) (interface{}, error) {
+		m, ok := args[0].(*Map)
+		if !ok {
+			return nil, diag.Errorf(diag.TypeError, "Can only get the values of a map.")
+		}
+		values := make([]interface{}, 0, m.Len())
+		for _, key := range m.keys {
+			values = append(values, m.entries[key])
+		}
+		return &List{Elements: values}, nil
+	}},
+	// entries returns a list of [key, value] pairs to iterate over a map
+	{Name: "entries", Arity: 1, Fn: func(args []interface{}) (interface{}, error) {
+		m, ok := args[0].(*Map)
+		if !ok {
+			return nil, diag.Errorf(diag.TypeError, "Can only get the entries of a map.")
+		}
+		entries := make([]interface{}, 0, m.Len())
+		for _, key := range m.keys {
+			entries = append(entries, &List{Elements: []interface{}{key, m.entries[key]}})
+		}
+		return &List{Elements: entries}, nil
+	}},
+	// has reports whether a map holds a key
+	{Name: "has", Arity: 2, Fn: func(args []interface{}) (interface{}, error) {
+		m, ok := args[0].(*Map)
+		if !ok {
+			return nil, diag.Errorf(diag.TypeError, "Can only look up keys in a map.")
+		}
+		return m.Has(args[1]), nil
+	}},
+	// delete removes a key from a map and reports whether it was present
+	{Name: "delete", Arity: 2, Fn: func(args []interface{}) (interface{}, error) {
+		m, ok := args[0].(*Map)
+		if !ok {
+			return nil, diag.Errorf(diag.TypeError, "Can only delete keys from a map.")
+		}
+		return m.Delete(args[1]), nil
+	}},
+	// Error creates an error value carrying a message, its line
+	// is set to the line of the 'throw' statement throwing it
+	{Name: "Error", Arity: 1, Fn: func(args []interface{}) (interface{}, error) {
+		return &Error{Message: Stringify(args[0])}, nil
+	}},
+}, stringNatives...)
@@ -0,0 +1,190 @@
+package value
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/taki-mekhalfa/golox/diag"
+)
+
+// strings are indexed and sliced by code points, not by bytes
+
+// StringIndex returns the character of s at index as a string
+func StringIndex(s string, index interface{}) (interface{}, error) {
+	i, err := ToInteger(index)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(s)
+	if i < 0 || i >= len(runes) {
+		return nil, diag.Errorf(diag.IndexOutOfRange, "Index out of range.")
+	}
+	return string(runes[i]), nil
+}
+
+// StringSlice returns the characters of s between start (included) and
+// end (excluded), a <nil> bound defaults to the string's boundary.
+func StringSlice(s string, start, end interface{}) (string, error) {
+	runes := []rune(s)
+	low, high, err := sliceBounds(start, end, len(runes))
+	if err != nil {
+		return "", err
+	}
+	return string(runes[low:high]), nil
+}
+
+// indexOf returns the index of the first code point of sub in s, -1 if s doesn't contain it
+func indexOf(s, sub string) int {
+	i := strings.Index(s, sub)
+	if i < 0 {
+		return -1
+	}
+	return utf8.RuneCountInString(s[:i])
+}
+
+// stringArguments checks the arguments of the native name are strings
+func stringArguments(name string, args ...interface{}) ([]string, error) {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			if len(args) == 1 {
+				return nil, diag.Errorf(diag.TypeError, "Argument of %s must be a string.", name)
+			}
+			return nil, diag.Errorf(diag.TypeError, "Arguments of %s must be strings.", name)
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+// stringFunc adapts a function of strings to a native
+func stringFunc(name string, arity int, fn func(strs []string) interface{}) *Native {
+	return &Native{Name: name, Arity: arity, Fn: func(args []interface{}) (interface{}, error) {
+		strs, err := stringArguments(name, args...)
+		if err != nil {
+			return nil, err
+		}
+		return fn(strs), nil
+	}}
+}
+
+// stringNatives are the built-in functions working on strings
+var stringNatives = []*Native{
+	// substr returns the characters between start (included) and end (excluded)
+	{Name: "substr", Arity: 3, Fn: func(args []interface{}) (interface{}, error) {
+		s, err := stringArguments("substr", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return StringSlice(s[0], args[1], args[2])
+	}},
+	stringFunc("indexOf", 2, func(strs []string) interface{} {
+		return float64(indexOf(strs[0], strs[1]))
+	}),
+	// split returns the list of the substrings between the separators,
+	// an empty separator splits the string into its characters
+	stringFunc("split", 2, func(strs []string) interface{} {
+		parts := strings.Split(strs[0], strs[1])
+		elements := make([]interface{}, len(parts))
+		for i, part := range parts {
+			elements[i] = part
+		}
+		return &List{Elements: elements}
+	}),
+	// join concatenates the elements of a list with a separator between them
+	{Name: "join", Arity: 2, Fn: func(args []interface{}) (interface{}, error) {
+		l, ok := args[0].(*List)
+		if !ok {
+			return nil, diag.Errorf(diag.TypeError, "Can only join the elements of a list.")
+		}
+		sep, err := stringArguments("join", args[1])
+		if err != nil {
+			return nil, err
+		}
+		parts := make([]string, len(l.Elements))
+		for i, element := range l.Elements {
+			parts[i] = Stringify(element)
+		}
+		return strings.Join(parts, sep[0]), nil
+	}},
+	stringFunc("upper", 1, func(strs []string) interface{} {
+		return strings.ToUpper(strs[0])
+	}),
+	stringFunc("lower", 1, func(strs []string) interface{} {
+		return strings.ToLower(strs[0])
+	}),
+	// trim removes the leading and trailing white space
+	stringFunc("trim", 1, func(strs []string) interface{} {
+		return strings.TrimSpace(strs[0])
+	}),
+	// replace replaces all the occurrences of old by new
+	stringFunc("replace", 3, func(strs []string) interface{} {
+		return strings.ReplaceAll(strs[0], strs[1], strs[2])
+	}),
+	stringFunc("startsWith", 2, func(strs []string) interface{} {
+		return strings.HasPrefix(strs[0], strs[1])
+	}),
+	stringFunc("endsWith", 2, func(strs []string) interface{} {
+		return strings.HasSuffix(strs[0], strs[1])
+	}),
+	// repeat concatenates count copies of a string
+	{Name: "repeat", Arity: 2, Fn: func(args []interface{}) (interface{}, error) {
+		s, err := stringArguments("repeat", args[0])
+		if err != nil {
+			return nil, err
+		}
+		count, err := ToInteger(args[1])
+		if err != nil || count < 0 {
+			return nil, diag.Errorf(diag.TypeError, "Count must be a non-negative integer.")
+		}
+		// the length is computed on floats, the count can overflow an int
+		if float64(len(s[0]))*args[1].(float64) > MaxStringLength {
+			return nil, diag.Errorf(diag.TypeError, "Repeated string is too long.")
+		}
+		return strings.Repeat(s[0], count), nil
+	}},
+	// charCode returns the code point of a string of one character
+	{Name: "charCode", Arity: 1, Fn: func(args []interface{}) (interface{}, error) {
+		s, ok := args[0].(string)
+		if !ok || utf8.RuneCountInString(s) != 1 {
+			return nil, diag.Errorf(diag.TypeError, "Argument of charCode must be a string of one character.")
+		}
+		r, _ := utf8.DecodeRuneInString(s)
+		return float64(r), nil
+	}},
+	// fromCharCode returns the string of one character with the code point
+	{Name: "fromCharCode", Arity: 1, Fn: func(args []interface{}) (interface{}, error) {
+		code, err := ToInteger(args[0])
+		if err != nil || !utf8.ValidRune(rune(code)) || code != int(rune(code)) {
+			return nil, diag.Errorf(diag.TypeError, "Invalid character code %s.", Stringify(args[0]))
+		}
+		return string(rune(code)), nil
+	}},
+	// str returns the text of a value as 'print' writes it
+	{Name: "str", Arity: 1, Fn: func(args []interface{}) (interface{}, error) {
+		return Stringify(args[0]), nil
+	}},
+	// num parses a number written in decimal, surrounding white space is ignored
+	{Name: "num", Arity: 1, Fn: func(args []interface{}) (interface{}, error) {
+		s, err := stringArguments("num", args[0])
+		if err != nil {
+			return nil, err
+		}
+		text := strings.TrimSpace(s[0])
+		n, err := strconv.ParseFloat(text, 64)
+		// strconv also parses hexadecimal numbers, infinities and NaN
+		if err != nil && !isRangeError(err) || strings.Trim(text, "+-.0123456789eE") != "" {
+			return nil, diag.Errorf(diag.ConversionError, "Can't convert '%s' to a number.", s[0])
+		}
+		return n, nil
+	}},
+}
+
+// isRangeError reports whether strconv failed because the number is
+// too large, the number is then parsed as an infinity
+func isRangeError(err error) bool {
+	e, ok := err.(*strconv.NumError)
+	return ok && e.Err == strconv.ErrRange
+}
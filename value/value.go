@@ -0,0 +1,125 @@
+// Package value holds the runtime values and the built-in functions
+// shared by the tree-walk interpreter and the bytecode virtual machine.
+package value
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// DefaultMaxDepth is the default maximum number of nested calls,
+// deeper calls raise a stack overflow error.
+const DefaultMaxDepth = 1 << 14
+
+// MaxStringLength is the length in bytes of the longest
+// string built by repeat, longer ones raise an error
+const MaxStringLength = 1 << 28
+
+// Truthy returns true if v is true and false otherwise.
+// everything is true expect for a boolean false or a <nil>
+func Truthy(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if boolValue, ok := v.(bool); ok {
+		return boolValue
+	}
+
+	return true
+}
+
+// Stringify returns the text of v as the 'print' statement writes it,
+// integral numbers are written without a fractional part or an exponent
+// and the infinities and NaN as the constants of math: inf, -inf and nan
+func Stringify(v interface{}) string {
+	return stringify(v, nil)
+}
+
+// stringify returns the text of v, printing holds the lists and maps
+// being written around it. a list or a map met again contains itself
+// and is written [...] or {...}
+func stringify(v interface{}, printing []interface{}) string {
+	switch v := v.(type) {
+	case *List:
+		return v.stringify(printing)
+	case *Map:
+		return v.stringify(printing)
+	case nil:
+		return "nil"
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return "inf"
+		case math.IsInf(v, -1):
+			return "-inf"
+		case math.IsNaN(v):
+			return "nan"
+		}
+		if v == math.Trunc(v) && math.Abs(v) < 1e21 {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// Equaler is implemented by the values equal to values other than themselves
+type Equaler interface {
+	Equal(v interface{}) bool
+}
+
+// Equal reports whether a and b are equal for '==' and '!='.
+// nil is only equal to nil, booleans and strings are equal when they
+// have the same value, numbers follow IEEE 754 (NaN is not equal to
+// itself and 0 is equal to -0) and values of different types are never
+// equal. lists, maps, instances, classes and functions are only equal
+// to themselves, methods are equal when bound to the same instance.
+func Equal(a, b interface{}) bool {
+	if e, ok := a.(Equaler); ok {
+		return e.Equal(b)
+	}
+	return a == b
+}
+
+// Comparison is one of the comparison operators
+type Comparison int
+
+const (
+	Less Comparison = iota
+	LessEqual
+	Greater
+	GreaterEqual
+)
+
+// Compare applies c to a and b, numbers are compared by value and
+// strings lexicographically byte by byte. ok is false unless a and b
+// are two numbers or two strings.
+func Compare(c Comparison, a, b interface{}) (result, ok bool) {
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok {
+			return holds(c, a < b, a == b, a > b), true
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return holds(c, a < b, a == b, a > b), true
+		}
+	}
+	return false, false
+}
+
+// holds reports whether c holds for operands ordered as given,
+// the three are false for NaN which is not ordered
+func holds(c Comparison, less, equal, greater bool) bool {
+	switch c {
+	case Less:
+		return less
+	case LessEqual:
+		return less || equal
+	case Greater:
+		return greater
+	default:
+		return greater || equal
+	}
+}
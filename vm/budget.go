@@ -0,0 +1,30 @@
+package vm
+
+import (
+	"github.com/taki-mekhalfa/golox/diag"
+)
+
+// tick is called at loop back-edges and calls, it returns an
+// error when the execution is cancelled or out of steps
+func (vm *VM) tick() error {
+	vm.steps++
+	if vm.MaxSteps != 0 && vm.steps > vm.MaxSteps {
+		return diag.Errorf(diag.BudgetExceeded, "Execution budget exceeded: too many steps.")
+	}
+	select {
+	case <-vm.ctx.Done():
+		return diag.Errorf(diag.Cancelled, "Execution cancelled: %v.", vm.ctx.Err())
+	default:
+	}
+	return nil
+}
+
+// alloc is called when a list, map, string, instance or function is
+// created, it returns an error when the execution is out of allocations
+func (vm *VM) alloc() error {
+	vm.allocations++
+	if vm.MaxAllocations != 0 && vm.allocations > vm.MaxAllocations {
+		return diag.Errorf(diag.BudgetExceeded, "Execution budget exceeded: too many allocations.")
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package vm
+
+import "github.com/taki-mekhalfa/golox/token"
+
+// opcode is a bytecode instruction, its operands follow it in the chunk.
+// unless stated otherwise, operands are 2 bytes long.
+type opcode byte
+
+const (
+	// push the constant at the operand index
+	opConstant opcode = iota
+	opNil
+	opTrue
+	opFalse
+	opPop
+
+	// 1 byte operand: the slot of the local in the current frame
+	opGetLocal
+	opSetLocal
+	// operand: the constant holding the global's name
+	opGetGlobal
+	opDefineGlobal
+	opSetGlobal
+	// 1 byte operand: the index of the upvalue in the current closure
+	opGetUpvalue
+	opSetUpvalue
+	// operand: the constant holding the property's name
+	opGetProperty
+	opSetProperty
+	opGetSuper
+
+	opEqual
+	opGreater
+	opGreaterEqual
+	opLess
+	opLessEqual
+	opAdd
+	opSubtract
+	opMultiply
+	opDivide
+	opNot
+	opNegate
+
+	opPrint
+
+	// operand: the forward offset to jump by
+	opJump
+	opJumpIfFalse
+	// operand: the backward offset to jump by
+	opLoop
+
+	// 1 byte operand: the number of arguments
+	opCall
+	// operand: the constant holding the function, followed by
+	// a pair of bytes (is local, index) for each captured upvalue
+	opClosure
+	opCloseUpvalue
+	opReturn
+
+	// operand: the constant holding the class's name
+	opClass
+	opInherit
+	// operand: the constant holding the method's name
+	opMethod
+
+	// operand: the number of elements
+	opList
+	// operand: the number of key/value pairs
+	opMap
+	opIndex
+	opSetIndex
+	opSlice
+
+	opThrow
+	// rethrow the value and the line left on the stack by a handler
+	opRethrow
+	// operand: the forward offset to the handler's code
+	opPushHandler
+	opPopHandler
+
+	// operands: the constants holding the module's path and name
+	opImport
+)
+
+// chunk is a sequence of bytecode with its constants pool
+type chunk struct {
+	code []byte
+	// spans holds the span of source each byte was compiled from
+	spans     []token.Span
+	constants []interface{}
+}
+
+func (c *chunk) write(b byte, span token.Span) {
+	c.code = append(c.code, b)
+	c.spans = append(c.spans, span)
+}
+
+// addConstant adds a value to the constants pool and returns its index,
+// strings are interned to not grow the pool with duplicate names.
+func (c *chunk) addConstant(v interface{}) int {
+	if s, ok := v.(string); ok {
+		for i, constant := range c.constants {
+			if constant == s {
+				return i
+			}
+		}
+	}
+	c.constants = append(c.constants, v)
+	return len(c.constants) - 1
+}
@@ -0,0 +1,792 @@
+package vm
+
+import (
+	. "github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/token"
+)
+
+const (
+	// locals and upvalues are addressed with a single byte
+	maxLocals   = 256
+	maxUpvalues = 256
+	maxArgs     = 255
+	// constants and jumps are addressed with two bytes
+	maxConstants = 1 << 16
+	maxJump      = 1<<16 - 1
+)
+
+type functionKind int
+
+const (
+	scriptKind functionKind = iota
+	functionKind_
+	methodKind
+)
+
+type local struct {
+	name  string
+	depth int
+	// captured locals are closed over when they go out of scope
+	captured bool
+}
+
+type upvalueRef struct {
+	index   byte
+	isLocal bool
+}
+
+type loop struct {
+	// start is the offset of the loop's condition
+	start int
+	// depth is the scope depth the loop is in
+	depth int
+	// tries is the number of try blocks the loop is in
+	tries        int
+	hasIncrement bool
+	// jumps to the increment and to the end of the loop to patch
+	continues []int
+	breaks    []int
+}
+
+// tryBlock is a try statement whose body or catch clause is being compiled
+type tryBlock struct {
+	// finally is nil when there is no finally clause
+	finally *Block
+}
+
+// funcScope holds the compilation state of a function
+type funcScope struct {
+	enclosing *funcScope
+	function  *function
+	kind      functionKind
+	locals    []local
+	upvalues  []upvalueRef
+	depth     int
+	loops     []*loop
+	tries     []*tryBlock
+}
+
+// compiler compiles resolved statements into bytecode.
+// it assumes the statements went through the resolver
+// and are free of semantic errors.
+type compiler struct {
+	diags diag.List
+
+	scope *funcScope
+	// class is the name of the class whose methods are being compiled
+	class string
+	// span is the span of source the emitted instructions are compiled from
+	span token.Span
+}
+
+// compile compiles stmts into the function of a script, the
+// returned error is a diag.List of the compilation errors.
+func (c *compiler) compile(stmts []Stmt) (*function, error) {
+	c.beginFunction("<script>", scriptKind)
+	for _, stmt := range stmts {
+		c.compileStmt(stmt)
+	}
+	function, _ := c.endFunction()
+	return function, c.diags.Err()
+}
+
+func (c *compiler) beginFunction(name string, kind functionKind) {
+	c.scope = &funcScope{
+		enclosing: c.scope,
+		function:  &function{name: name},
+		kind:      kind,
+	}
+	// the first slot holds the called function,
+	// or the receiver of a method.
+	slot0 := ""
+	if kind == methodKind {
+		slot0 = "this"
+	}
+	c.scope.locals = append(c.scope.locals, local{name: slot0})
+}
+
+func (c *compiler) endFunction() (*function, []upvalueRef) {
+	// return <nil> when falling off the end of the function
+	c.emitOp(opNil)
+	c.emitOp(opReturn)
+
+	scope := c.scope
+	c.scope = scope.enclosing
+	scope.function.upvalueCount = len(scope.upvalues)
+	return scope.function, scope.upvalues
+}
+
+func (c *compiler) function(f *Function, kind functionKind) {
+	name := f.Name.Lexeme
+	// lambdas are named after their 'fun' keyword
+	if f.Name.Type == token.FUN {
+		name = ""
+	}
+
+	c.beginFunction(name, kind)
+	if kind == methodKind {
+		c.scope.function.class = c.class
+	}
+	c.beginScope()
+	for _, param := range f.Params {
+		c.addLocal(param.Lexeme)
+	}
+	c.scope.function.arity = len(f.Params)
+	for _, stmt := range f.Body {
+		c.compileStmt(stmt)
+	}
+	function, upvalues := c.endFunction()
+
+	c.span = token.SpanOf(f.Name)
+	c.emitShort(opClosure, c.makeConstant(function))
+	for _, upvalue := range upvalues {
+		isLocal := byte(0)
+		if upvalue.isLocal {
+			isLocal = 1
+		}
+		c.emit(isLocal, upvalue.index)
+	}
+}
+
+func (c *compiler) VisitPrint(p *Print) interface{} {
+	c.compileExpr(p.Expr)
+	c.emitOp(opPrint)
+	return nil
+}
+
+func (c *compiler) VisitExprStmt(es *ExprStmt) interface{} {
+	c.compileExpr(es.Expr)
+	c.emitOp(opPop)
+	return nil
+}
+
+func (c *compiler) VisitVarStmt(v *VarStmt) interface{} {
+	if v.Initializer != nil {
+		c.compileExpr(v.Initializer)
+	} else {
+		c.emitOp(opNil)
+	}
+	c.span = token.SpanOf(v.Token)
+	c.defineVariable(v.Name)
+	return nil
+}
+
+func (c *compiler) VisitBlock(b *Block) interface{} {
+	c.beginScope()
+	for _, stmt := range b.Content {
+		c.compileStmt(stmt)
+	}
+	c.endScope()
+	return nil
+}
+
+func (c *compiler) VisitIf(if_ *If) interface{} {
+	c.compileExpr(if_.Condition)
+	thenJump := c.emitJump(opJumpIfFalse)
+	c.emitOp(opPop)
+	c.compileStmt(if_.Then)
+
+	elseJump := c.emitJump(opJump)
+	c.patchJump(thenJump)
+	c.emitOp(opPop)
+	if if_.Else != nil {
+		c.compileStmt(if_.Else)
+	}
+	c.patchJump(elseJump)
+	return nil
+}
+
+func (c *compiler) VisitWhile(while *While) interface{} {
+	l := &loop{
+		start:        len(c.chunk().code),
+		depth:        c.scope.depth,
+		tries:        len(c.scope.tries),
+		hasIncrement: while.Increment != nil,
+	}
+
+	c.compileExpr(while.Condition)
+	exitJump := c.emitJump(opJumpIfFalse)
+	c.emitOp(opPop)
+
+	c.scope.loops = append(c.scope.loops, l)
+	c.compileStmt(while.Body)
+	c.scope.loops = c.scope.loops[:len(c.scope.loops)-1]
+
+	if while.Increment != nil {
+		for _, jump := range l.continues {
+			c.patchJump(jump)
+		}
+		c.compileExpr(while.Increment)
+		c.emitOp(opPop)
+	}
+	c.emitLoop(l.start)
+
+	c.patchJump(exitJump)
+	c.emitOp(opPop)
+	// the condition was already popped when breaking
+	for _, jump := range l.breaks {
+		c.patchJump(jump)
+	}
+	return nil
+}
+
+func (c *compiler) VisitBreak(b *Break) interface{} {
+	l := c.scope.loops[len(c.scope.loops)-1]
+	c.span = token.SpanOf(b.Keyword)
+	c.exitLoop(l)
+	l.breaks = append(l.breaks, c.emitJump(opJump))
+	return nil
+}
+
+func (c *compiler) VisitContinue(cont *Continue) interface{} {
+	l := c.scope.loops[len(c.scope.loops)-1]
+	c.span = token.SpanOf(cont.Keyword)
+	c.exitLoop(l)
+	if l.hasIncrement {
+		l.continues = append(l.continues, c.emitJump(opJump))
+	} else {
+		c.emitLoop(l.start)
+	}
+	return nil
+}
+
+// exitLoop emits the instructions leaving the try blocks
+// and discarding the locals of the body of a loop
+func (c *compiler) exitLoop(l *loop) {
+	c.exitTries(l.tries)
+	for i := len(c.scope.locals) - 1; i >= 0 && c.scope.locals[i].depth > l.depth; i-- {
+		if c.scope.locals[i].captured {
+			c.emitOp(opCloseUpvalue)
+		} else {
+			c.emitOp(opPop)
+		}
+	}
+}
+
+func (c *compiler) VisitFunction(f *Function) interface{} {
+	// a local function is declared before its body
+	// is compiled so that it can refer to itself
+	if c.scope.depth > 0 {
+		c.addLocal(f.Name.Lexeme)
+	}
+	c.function(f, functionKind_)
+	if c.scope.depth == 0 {
+		c.emitShort(opDefineGlobal, c.makeConstant(f.Name.Lexeme))
+	}
+	return nil
+}
+
+func (c *compiler) VisitReturn(r *Return) interface{} {
+	if r.Value != nil {
+		c.compileExpr(r.Value)
+	} else {
+		c.emitOp(opNil)
+	}
+	c.span = token.SpanOf(r.Token)
+
+	if len(c.scope.tries) > 0 {
+		// the returned value is kept in a hidden local
+		// while leaving the enclosing try blocks
+		c.scope.locals = append(c.scope.locals, local{depth: c.scope.depth})
+		c.exitTries(0)
+		c.scope.locals = c.scope.locals[:len(c.scope.locals)-1]
+	}
+	c.emitOp(opReturn)
+	return nil
+}
+
+func (c *compiler) VisitClass(cl *Class) interface{} {
+	c.span = token.SpanOf(cl.Name)
+	if c.scope.depth > 0 {
+		c.addLocal(cl.Name.Lexeme)
+	}
+	c.emitShort(opClass, c.makeConstant(cl.Name.Lexeme))
+	if c.scope.depth == 0 {
+		c.emitShort(opDefineGlobal, c.makeConstant(cl.Name.Lexeme))
+	}
+
+	if cl.Superclass != nil {
+		c.compileExpr(cl.Superclass)
+		// the superclass stays on the stack as a local
+		// named "super" that methods close on.
+		c.beginScope()
+		c.addLocal("super")
+		c.getVariable(cl.Name)
+		c.span = token.SpanOf(cl.Superclass.Token)
+		c.emitOp(opInherit)
+	}
+
+	c.getVariable(cl.Name)
+	enclosing := c.class
+	c.class = cl.Name.Lexeme
+	for _, method := range cl.Methods {
+		c.function(method, methodKind)
+		c.emitShort(opMethod, c.makeConstant(method.Name.Lexeme))
+	}
+	c.class = enclosing
+	c.emitOp(opPop)
+
+	if cl.Superclass != nil {
+		c.endScope()
+	}
+	return nil
+}
+
+func (c *compiler) VisitThrow(t *Throw) interface{} {
+	c.compileExpr(t.Value)
+	c.span = t.Position()
+	c.emitOp(opThrow)
+	return nil
+}
+
+// a try statement is compiled to:
+//
+//	    push handler -> catch
+//	    body
+//	    pop handler
+//	    jump -> finally
+//	catch: (the thrown value and where it was raised are on the stack)
+//	    push handler -> rethrow
+//	    catch block
+//	    pop handler
+//	    jump -> finally
+//	rethrow: (the thrown value and where it was raised are on the stack)
+//	    finally block
+//	    rethrow
+//	finally:
+//	    finally block
+//
+// the finally block is also inlined before any return, break
+// or continue jumping out of the try statement.
+func (c *compiler) VisitTry(t *Try) interface{} {
+	handler := c.enterTry(t.Finally)
+	c.compileStmt(t.Body)
+	c.exitTry()
+	toFinally := []int{c.emitJump(opJump)}
+
+	c.patchJump(handler)
+	if t.Catch != nil {
+		c.beginScope()
+		c.addLocal(t.CatchParam.Lexeme)
+		c.addLocal("")
+
+		rethrow := -1
+		if t.Finally != nil {
+			rethrow = c.enterTry(t.Finally)
+		}
+		c.compileStmt(t.Catch)
+		if t.Finally != nil {
+			c.exitTry()
+		}
+		c.endScope()
+		toFinally = append(toFinally, c.emitJump(opJump))
+
+		if t.Finally != nil {
+			c.patchJump(rethrow)
+		}
+	}
+
+	if t.Finally != nil {
+		// the handler's code runs the finally block and rethrows
+		c.beginScope()
+		c.addLocal("")
+		c.addLocal("")
+		c.compileStmt(t.Finally)
+		c.emitOp(opRethrow)
+		// the locals are never popped as rethrowing unwinds the stack
+		c.scope.depth--
+		c.scope.locals = c.scope.locals[:len(c.scope.locals)-2]
+	}
+
+	for _, jump := range toFinally {
+		c.patchJump(jump)
+	}
+	if t.Finally != nil {
+		c.compileStmt(t.Finally)
+	}
+	return nil
+}
+
+// enterTry emits the instruction pushing an exception handler
+// and returns the jump to patch to the handler's code
+func (c *compiler) enterTry(finally *Block) int {
+	c.scope.tries = append(c.scope.tries, &tryBlock{finally: finally})
+	return c.emitJump(opPushHandler)
+}
+
+func (c *compiler) exitTry() {
+	c.emitOp(opPopHandler)
+	c.scope.tries = c.scope.tries[:len(c.scope.tries)-1]
+}
+
+// exitTries emits the instructions leaving the innermost try blocks
+// down to the given number of try blocks, running their finally blocks
+func (c *compiler) exitTries(to int) {
+	tries := c.scope.tries
+	for i := len(tries) - 1; i >= to; i-- {
+		c.emitOp(opPopHandler)
+		if tries[i].finally != nil {
+			// the finally block runs outside of its try block
+			c.scope.tries = tries[:i]
+			c.compileStmt(tries[i].finally)
+			c.scope.tries = tries
+		}
+	}
+}
+
+func (c *compiler) VisitImport(imp *Import) interface{} {
+	c.span = imp.Position()
+	c.emitShort(opImport, c.makeConstant(imp.Path))
+	name := c.makeConstant(imp.Name.Lexeme)
+	c.emit(byte(name>>8), byte(name))
+	c.defineVariable(imp.Name.Lexeme)
+	return nil
+}
+
+func (c *compiler) VisitBinary(b *Binary) interface{} {
+	c.compileExpr(b.Left)
+	c.compileExpr(b.Right)
+	c.span = b.Position()
+	switch b.Operator.Type {
+	case token.PLUS:
+		c.emitOp(opAdd)
+	case token.MINUS:
+		c.emitOp(opSubtract)
+	case token.STAR:
+		c.emitOp(opMultiply)
+	case token.SLASH:
+		c.emitOp(opDivide)
+	case token.GREATER:
+		c.emitOp(opGreater)
+	case token.GREATER_EQUAL:
+		c.emitOp(opGreaterEqual)
+	case token.LESS:
+		c.emitOp(opLess)
+	case token.LESS_EQUAL:
+		c.emitOp(opLessEqual)
+	case token.EQUAL_EQUAL:
+		c.emitOp(opEqual)
+	case token.BANG_EQUAL:
+		c.emitOp(opEqual)
+		c.emitOp(opNot)
+	}
+	return nil
+}
+
+func (c *compiler) VisitLogical(l *Logical) interface{} {
+	c.compileExpr(l.Left)
+	switch l.Operator.Type {
+	case token.AND:
+		end := c.emitJump(opJumpIfFalse)
+		c.emitOp(opPop)
+		c.compileExpr(l.Right)
+		c.patchJump(end)
+	case token.OR:
+		elseJump := c.emitJump(opJumpIfFalse)
+		end := c.emitJump(opJump)
+		c.patchJump(elseJump)
+		c.emitOp(opPop)
+		c.compileExpr(l.Right)
+		c.patchJump(end)
+	}
+	return nil
+}
+
+func (c *compiler) VisitUnary(u *Unary) interface{} {
+	c.compileExpr(u.Expr)
+	c.span = u.Position()
+	switch u.Operator.Type {
+	case token.MINUS:
+		c.emitOp(opNegate)
+	case token.BANG:
+		c.emitOp(opNot)
+	}
+	return nil
+}
+
+func (c *compiler) VisitGrouping(g *Grouping) interface{} {
+	c.compileExpr(g.Expr)
+	return nil
+}
+
+func (c *compiler) VisitLiteral(l *Literal) interface{} {
+	switch l.Value {
+	case nil:
+		c.emitOp(opNil)
+	case true:
+		c.emitOp(opTrue)
+	case false:
+		c.emitOp(opFalse)
+	default:
+		c.emitShort(opConstant, c.makeConstant(l.Value))
+	}
+	return nil
+}
+
+func (c *compiler) VisitVar(v *Var) interface{} {
+	c.getVariable(v.Token)
+	return nil
+}
+
+func (c *compiler) VisitAssign(a *Assign) interface{} {
+	c.compileExpr(a.Value)
+	c.span = token.SpanOf(a.Identifier)
+	if slot := c.scope.resolveLocal(a.Identifier.Lexeme); slot != -1 {
+		c.emit(byte(opSetLocal), byte(slot))
+	} else if index := c.resolveUpvalue(c.scope, a.Identifier.Lexeme); index != -1 {
+		c.emit(byte(opSetUpvalue), byte(index))
+	} else {
+		c.emitShort(opSetGlobal, c.makeConstant(a.Identifier.Lexeme))
+	}
+	return nil
+}
+
+func (c *compiler) VisitCall(call *Call) interface{} {
+	c.compileExpr(call.Callee)
+	for _, arg := range call.Args {
+		c.compileExpr(arg)
+	}
+	c.span = call.Position()
+	if len(call.Args) > maxArgs {
+		c.reportError(diag.TooManyArguments, "Can't have more than 255 arguments.")
+	}
+	c.emit(byte(opCall), byte(len(call.Args)))
+	return nil
+}
+
+func (c *compiler) VisitGet(g *Get) interface{} {
+	c.compileExpr(g.Object)
+	c.span = token.SpanOf(g.Property)
+	c.emitShort(opGetProperty, c.makeConstant(g.Property.Lexeme))
+	return nil
+}
+
+func (c *compiler) VisitSet(s *Set) interface{} {
+	c.compileExpr(s.Object)
+	c.compileExpr(s.Value)
+	c.span = token.SpanOf(s.Property)
+	c.emitShort(opSetProperty, c.makeConstant(s.Property.Lexeme))
+	return nil
+}
+
+func (c *compiler) VisitThis(this *This) interface{} {
+	c.getVariable(this.Keyword)
+	return nil
+}
+
+func (c *compiler) VisitSuper(s *Super) interface{} {
+	c.getVariable(token.Token{Type: token.THIS, Lexeme: "this", Line: s.Keyword.Line})
+	c.getVariable(s.Keyword)
+	c.span = token.SpanOf(s.Method)
+	c.emitShort(opGetSuper, c.makeConstant(s.Method.Lexeme))
+	return nil
+}
+
+func (c *compiler) VisitList(l *List) interface{} {
+	for _, element := range l.Elements {
+		c.compileExpr(element)
+	}
+	c.span = l.Position()
+	c.emitShort(opList, len(l.Elements))
+	return nil
+}
+
+func (c *compiler) VisitMap(m *Map) interface{} {
+	for i := range m.Keys {
+		c.compileExpr(m.Keys[i])
+		c.compileExpr(m.Values[i])
+	}
+	c.span = m.Position()
+	c.emitShort(opMap, len(m.Keys))
+	return nil
+}
+
+func (c *compiler) VisitIndex(i *Index) interface{} {
+	c.compileExpr(i.Object)
+	c.compileExpr(i.Index)
+	c.span = i.Position()
+	c.emitOp(opIndex)
+	return nil
+}
+
+func (c *compiler) VisitSlice(s *Slice) interface{} {
+	c.compileExpr(s.Object)
+	// an omitted bound is a <nil>
+	for _, bound := range []Expr{s.Start, s.End} {
+		if bound != nil {
+			c.compileExpr(bound)
+		} else {
+			c.emitOp(opNil)
+		}
+	}
+	c.span = s.Position()
+	c.emitOp(opSlice)
+	return nil
+}
+
+func (c *compiler) VisitSetIndex(s *SetIndex) interface{} {
+	c.compileExpr(s.Object)
+	c.compileExpr(s.Index)
+	c.compileExpr(s.Value)
+	c.span = s.Position()
+	c.emitOp(opSetIndex)
+	return nil
+}
+
+func (c *compiler) VisitLambda(l *Lambda) interface{} {
+	c.function(l.Function, functionKind_)
+	return nil
+}
+
+// defineVariable binds the value on top of the stack to a new variable
+func (c *compiler) defineVariable(name string) {
+	if c.scope.depth > 0 {
+		// the value is left on the stack as the local's slot
+		c.addLocal(name)
+		return
+	}
+	c.emitShort(opDefineGlobal, c.makeConstant(name))
+}
+
+func (c *compiler) getVariable(name token.Token) {
+	c.span = token.SpanOf(name)
+	if slot := c.scope.resolveLocal(name.Lexeme); slot != -1 {
+		c.emit(byte(opGetLocal), byte(slot))
+	} else if index := c.resolveUpvalue(c.scope, name.Lexeme); index != -1 {
+		c.emit(byte(opGetUpvalue), byte(index))
+	} else {
+		c.emitShort(opGetGlobal, c.makeConstant(name.Lexeme))
+	}
+}
+
+func (c *compiler) addLocal(name string) {
+	if len(c.scope.locals) == maxLocals {
+		c.reportError(diag.TooManyLocals, "Too many local variables in function.")
+		return
+	}
+	c.scope.locals = append(c.scope.locals, local{name: name, depth: c.scope.depth})
+}
+
+func (s *funcScope) resolveLocal(name string) int {
+	for i := len(s.locals) - 1; i >= 0; i-- {
+		if s.locals[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolveUpvalue looks up a variable in the enclosing functions
+// and captures it in each function down to the given one
+func (c *compiler) resolveUpvalue(s *funcScope, name string) int {
+	if s.enclosing == nil {
+		return -1
+	}
+	if slot := s.enclosing.resolveLocal(name); slot != -1 {
+		s.enclosing.locals[slot].captured = true
+		return c.addUpvalue(s, byte(slot), true)
+	}
+	if index := c.resolveUpvalue(s.enclosing, name); index != -1 {
+		return c.addUpvalue(s, byte(index), false)
+	}
+	return -1
+}
+
+func (c *compiler) addUpvalue(s *funcScope, index byte, isLocal bool) int {
+	for i, upvalue := range s.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return i
+		}
+	}
+	if len(s.upvalues) == maxUpvalues {
+		c.reportError(diag.TooManyUpvalues, "Too many closure variables in function.")
+		return 0
+	}
+	s.upvalues = append(s.upvalues, upvalueRef{index: index, isLocal: isLocal})
+	return len(s.upvalues) - 1
+}
+
+func (c *compiler) beginScope() {
+	c.scope.depth++
+}
+
+func (c *compiler) endScope() {
+	c.scope.depth--
+	locals := c.scope.locals
+	for len(locals) > 0 && locals[len(locals)-1].depth > c.scope.depth {
+		if locals[len(locals)-1].captured {
+			c.emitOp(opCloseUpvalue)
+		} else {
+			c.emitOp(opPop)
+		}
+		locals = locals[:len(locals)-1]
+	}
+	c.scope.locals = locals
+}
+
+func (c *compiler) chunk() *chunk {
+	return &c.scope.function.chunk
+}
+
+func (c *compiler) emit(bytes ...byte) {
+	for _, b := range bytes {
+		c.chunk().write(b, c.span)
+	}
+}
+
+func (c *compiler) emitOp(op opcode) {
+	c.emit(byte(op))
+}
+
+func (c *compiler) emitShort(op opcode, operand int) {
+	c.emit(byte(op), byte(operand>>8), byte(operand))
+}
+
+func (c *compiler) makeConstant(v interface{}) int {
+	index := c.chunk().addConstant(v)
+	if index >= maxConstants {
+		c.reportError(diag.TooManyConstants, "Too many constants in one chunk.")
+		return 0
+	}
+	return index
+}
+
+// emitJump emits a jump with a placeholder offset
+// and returns the position of the offset to patch
+func (c *compiler) emitJump(op opcode) int {
+	c.emit(byte(op), 0xff, 0xff)
+	return len(c.chunk().code) - 2
+}
+
+// patchJump makes the jump at the given position land
+// on the next instruction to be emitted
+func (c *compiler) patchJump(offset int) {
+	jump := len(c.chunk().code) - offset - 2
+	if jump > maxJump {
+		c.reportError(diag.JumpTooLarge, "Too much code to jump over.")
+	}
+	c.chunk().code[offset] = byte(jump >> 8)
+	c.chunk().code[offset+1] = byte(jump)
+}
+
+func (c *compiler) emitLoop(start int) {
+	c.emitOp(opLoop)
+	offset := len(c.chunk().code) - start + 2
+	if offset > maxJump {
+		c.reportError(diag.JumpTooLarge, "Loop body too large.")
+	}
+	c.emit(byte(offset>>8), byte(offset))
+}
+
+func (c *compiler) compileStmt(stmt Stmt) {
+	stmt.Accept(c)
+}
+
+func (c *compiler) compileExpr(expr Expr) {
+	expr.Accept(c)
+}
+
+func (c *compiler) reportError(code diag.Code, errMessage string) {
+	c.diags = append(c.diags, diag.New(diag.Compile, c.span, code, errMessage))
+}
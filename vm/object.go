@@ -0,0 +1,111 @@
+package vm
+
+import "github.com/taki-mekhalfa/golox/diag"
+
+// function is the compiled form of a Lox function
+type function struct {
+	name string
+	// class is the name of the class declaring a method
+	class        string
+	arity        int
+	upvalueCount int
+	chunk        chunk
+}
+
+// traceName returns the name of the function in traces
+func (f *function) traceName() string {
+	if f.name == "" {
+		return "<fn>"
+	}
+	if f.class != "" {
+		return f.class + "." + f.name
+	}
+	return f.name
+}
+
+// String implements fmt.Stringer
+func (f *function) String() string {
+	if f.name == "" {
+		return "<fn>"
+	}
+	return "<fn " + f.name + ">"
+}
+
+// upvalue is a variable captured by a closure, it points to
+// a stack slot until the variable goes out of scope.
+type upvalue struct {
+	slot   int
+	closed interface{}
+	open   bool
+	// next is the next open upvalue down the stack
+	next *upvalue
+}
+
+type closure struct {
+	function *function
+	upvalues []*upvalue
+	// module holds the global variables the closure looks up
+	module *module
+}
+
+// String implements fmt.Stringer
+func (c *closure) String() string {
+	return c.function.String()
+}
+
+type class struct {
+	name    string
+	methods map[string]*closure
+}
+
+// String implements fmt.Stringer
+func (c *class) String() string {
+	return c.name + " class"
+}
+
+type instance struct {
+	klass  *class
+	fields map[string]interface{}
+}
+
+// String implements fmt.Stringer
+func (ins *instance) String() string {
+	return ins.klass.name + " instance"
+}
+
+type boundMethod struct {
+	receiver *instance
+	method   *closure
+}
+
+// String implements fmt.Stringer
+func (b *boundMethod) String() string {
+	return b.method.String()
+}
+
+// Equal implements value.Equaler, a method bound twice
+// to the same instance is equal to itself
+func (b *boundMethod) Equal(v interface{}) bool {
+	other, ok := v.(*boundMethod)
+	return ok && b.receiver == other.receiver && b.method == other.method
+}
+
+// module holds the global variables of a script,
+// it is the namespace object an imported file is bound to.
+type module struct {
+	name    string
+	file    string
+	globals map[string]interface{}
+}
+
+// String implements fmt.Stringer
+func (m *module) String() string {
+	return m.name + " module"
+}
+
+func (m *module) get(name string) (interface{}, error) {
+	if v, ok := m.globals[name]; ok {
+		return v, nil
+	}
+	return nil, diag.Errorf(diag.UndefinedProperty, "Undefined property '%s' in module '%s'.", name, m.name)
+}
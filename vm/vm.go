@@ -0,0 +1,871 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/taki-mekhalfa/golox/ast"
+	"github.com/taki-mekhalfa/golox/diag"
+	"github.com/taki-mekhalfa/golox/token"
+	"github.com/taki-mekhalfa/golox/value"
+)
+
+const (
+	init_ = "init"
+)
+
+// comparisons maps the comparison opcodes to their operator
+var comparisons = map[opcode]value.Comparison{
+	opLess:         value.Less,
+	opLessEqual:    value.LessEqual,
+	opGreater:      value.Greater,
+	opGreaterEqual: value.GreaterEqual,
+}
+
+type frame struct {
+	closure *closure
+	// ip is the offset of the next instruction to execute
+	ip int
+	// base is the stack slot of the called function
+	base int
+	// constructor frames return the instance in their first slot
+	constructor bool
+	// module is set for frames executing an imported module
+	module *module
+}
+
+// handler is pushed when entering a try statement
+// and tells where to resume when a value is thrown
+type handler struct {
+	frames   int
+	stackTop int
+	loading  int
+	ip       int
+}
+
+// VM is a stack-based virtual machine executing the bytecode compiled
+// from the resolved statements, it is an alternative to the tree-walk interpreter.
+type VM struct {
+	// File is the path of the script being interpreted,
+	// imported modules are first looked up relative to it.
+	File string
+	// SearchPath lists the directories where imported modules
+	// are looked up when they are not found relative to the importer.
+	SearchPath []string
+	// Load scans, parses and resolves the source of the module imported from path
+	Load func(path, src string) ([]ast.Stmt, error)
+	// Console holds the Stdout the 'print' statement writes
+	// to and the Stdin readLine and input read from
+	value.Console
+	// MaxDepth is the maximum number of nested calls, deeper calls
+	// raise a stack overflow error. value.DefaultMaxDepth is used when it is 0.
+	MaxDepth int
+	// MaxSteps bounds the number of loop iterations and calls,
+	// MaxAllocations the number of lists, maps, strings, instances
+	// and functions created by an execution, 0 means unbounded.
+	// exceeding them raises an error scripts can't catch.
+	MaxSteps       int
+	MaxAllocations int
+
+	stack    []interface{}
+	frames   []frame
+	handlers []handler
+	// openUpvalues is the list of the upvalues pointing
+	// to the stack, sorted from the top of the stack.
+	openUpvalues *upvalue
+
+	builtins map[string]interface{}
+	main     *module
+	// modules caches the imported modules by their absolute path
+	modules map[string]*module
+	// loading is the chain of modules being imported
+	loading []string
+
+	ctx         context.Context
+	steps       int
+	allocations int
+}
+
+func (vm *VM) Init() {
+	vm.builtins = make(map[string]interface{})
+	for _, native := range value.Natives {
+		vm.builtins[native.Name] = native
+	}
+	for _, native := range vm.Console.Natives() {
+		vm.builtins[native.Name] = native
+	}
+	for _, namespace := range value.Namespaces {
+		vm.builtins[namespace.Name] = namespace
+	}
+	vm.main = &module{globals: make(map[string]interface{})}
+	vm.modules = make(map[string]*module)
+}
+
+// Interpret compiles and runs stmts, the returned error is a diag.List
+// of the compilation errors or of the error the program stopped at.
+func (vm *VM) Interpret(stmts []ast.Stmt) error {
+	return vm.InterpretContext(context.Background(), stmts)
+}
+
+// InterpretContext is like Interpret but stops with a Cancelled
+// error when ctx is done, it is checked at loop back-edges and calls.
+func (vm *VM) InterpretContext(ctx context.Context, stmts []ast.Stmt) error {
+	vm.ctx, vm.steps, vm.allocations = ctx, 0, 0
+	compiler := compiler{}
+	function, err := compiler.compile(stmts)
+	if err != nil {
+		return err
+	}
+
+	vm.main.file = vm.File
+	closure := &closure{function: function, module: vm.main}
+	vm.push(closure)
+	vm.call(closure, 0)
+
+	if err := vm.run(); err != nil {
+		vm.reset()
+		return err
+	}
+	return nil
+}
+
+// Globals returns the global variables of the
+// script, the built-in functions are left out
+func (vm *VM) Globals() map[string]interface{} {
+	globals := make(map[string]interface{}, len(vm.main.globals))
+	for name, v := range vm.main.globals {
+		globals[name] = v
+	}
+	return globals
+}
+
+// reset clears the execution state after an uncaught error
+func (vm *VM) reset() {
+	vm.stack = vm.stack[:0]
+	vm.frames = vm.frames[:0]
+	vm.handlers = vm.handlers[:0]
+	vm.openUpvalues = nil
+	vm.loading = vm.loading[:0]
+}
+
+func (vm *VM) run() error {
+	fr := &vm.frames[len(vm.frames)-1]
+	code := fr.closure.function.chunk.code
+	constants := fr.closure.function.chunk.constants
+
+	// reload is called whenever the current frame changes
+	reload := func() {
+		fr = &vm.frames[len(vm.frames)-1]
+		code = fr.closure.function.chunk.code
+		constants = fr.closure.function.chunk.constants
+	}
+	readShort := func() int {
+		fr.ip += 2
+		return int(code[fr.ip-2])<<8 | int(code[fr.ip-1])
+	}
+	// fail throws a runtime error as an error value
+	fail := func(err error) error {
+		span := fr.closure.function.chunk.spans[fr.ip-1]
+		loxErr := &value.Error{Message: err.Error(), Line: span.Line, Code: diag.CodeOf(err), Causes: diag.CausesOf(err)}
+		if !diag.Catchable(loxErr.Code) {
+			return vm.uncaught(loxErr, vm.raisedAt(span))
+		}
+		if err := vm.throw(loxErr, vm.raisedAt(span)); err != nil {
+			return err
+		}
+		reload()
+		return nil
+	}
+
+	for {
+		op := opcode(code[fr.ip])
+		fr.ip++
+
+		switch op {
+		case opConstant:
+			vm.push(constants[readShort()])
+		case opNil:
+			vm.push(nil)
+		case opTrue:
+			vm.push(true)
+		case opFalse:
+			vm.push(false)
+		case opPop:
+			vm.pop()
+
+		case opGetLocal:
+			slot := int(code[fr.ip])
+			fr.ip++
+			vm.push(vm.stack[fr.base+slot])
+		case opSetLocal:
+			slot := int(code[fr.ip])
+			fr.ip++
+			vm.stack[fr.base+slot] = vm.peek(0)
+		case opGetGlobal:
+			name := constants[readShort()].(string)
+			v, ok := fr.closure.module.globals[name]
+			if !ok {
+				v, ok = vm.builtins[name]
+			}
+			if !ok {
+				if err := fail(diag.Errorf(diag.UndefinedVariable, "Undefined variable '%s'.", name)); err != nil {
+					return err
+				}
+				continue
+			}
+			vm.push(v)
+		case opDefineGlobal:
+			name := constants[readShort()].(string)
+			fr.closure.module.globals[name] = vm.pop()
+		case opSetGlobal:
+			name := constants[readShort()].(string)
+			globals := fr.closure.module.globals
+			// assigning a built-in defines a global shadowing it
+			_, builtin := vm.builtins[name]
+			if _, ok := globals[name]; !ok && !builtin {
+				if err := fail(diag.Errorf(diag.UndefinedVariable, "Undefined variable '%s'.", name)); err != nil {
+					return err
+				}
+				continue
+			}
+			globals[name] = vm.peek(0)
+		case opGetUpvalue:
+			upvalue := fr.closure.upvalues[code[fr.ip]]
+			fr.ip++
+			if upvalue.open {
+				vm.push(vm.stack[upvalue.slot])
+			} else {
+				vm.push(upvalue.closed)
+			}
+		case opSetUpvalue:
+			upvalue := fr.closure.upvalues[code[fr.ip]]
+			fr.ip++
+			if upvalue.open {
+				vm.stack[upvalue.slot] = vm.peek(0)
+			} else {
+				upvalue.closed = vm.peek(0)
+			}
+
+		case opGetProperty:
+			name := constants[readShort()].(string)
+			v, err := vm.getProperty(vm.peek(0), name)
+			if err != nil {
+				if err := fail(err); err != nil {
+					return err
+				}
+				continue
+			}
+			vm.stack[len(vm.stack)-1] = v
+		case opSetProperty:
+			name := constants[readShort()].(string)
+			ins, ok := vm.peek(1).(*instance)
+			if !ok {
+				if err := fail(diag.Errorf(diag.TypeError, "Only instances have fields.")); err != nil {
+					return err
+				}
+				continue
+			}
+			v := vm.pop()
+			ins.fields[name] = v
+			vm.stack[len(vm.stack)-1] = v
+		case opGetSuper:
+			name := constants[readShort()].(string)
+			superclass := vm.pop().(*class)
+			method, ok := superclass.methods[name]
+			if !ok {
+				if err := fail(diag.Errorf(diag.UndefinedProperty, "Undefined property '%s'.", name)); err != nil {
+					return err
+				}
+				continue
+			}
+			vm.stack[len(vm.stack)-1] = &boundMethod{receiver: vm.peek(0).(*instance), method: method}
+
+		case opEqual:
+			b, a := vm.pop(), vm.pop()
+			vm.push(value.Equal(a, b))
+		case opGreater, opGreaterEqual, opLess, opLessEqual:
+			result, ok := value.Compare(comparisons[op], vm.peek(1), vm.peek(0))
+			if !ok {
+				if err := fail(diag.Errorf(diag.TypeError, "Operands must be two numbers or two strings.")); err != nil {
+					return err
+				}
+				continue
+			}
+			vm.pop()
+			vm.stack[len(vm.stack)-1] = result
+		case opSubtract, opMultiply, opDivide:
+			a, aIsNumber := vm.peek(1).(float64)
+			b, bIsNumber := vm.peek(0).(float64)
+			if !aIsNumber || !bIsNumber {
+				if err := fail(diag.Errorf(diag.TypeError, "Operands must be both numbers.")); err != nil {
+					return err
+				}
+				continue
+			}
+			vm.pop()
+			var result interface{}
+			switch op {
+			case opSubtract:
+				result = a - b
+			case opMultiply:
+				result = a * b
+			case opDivide:
+				if b == 0 {
+					if err := fail(diag.Errorf(diag.DivisionByZero, "Divided by 0.")); err != nil {
+						return err
+					}
+					continue
+				}
+				result = a / b
+			}
+			vm.stack[len(vm.stack)-1] = result
+		case opAdd:
+			var result interface{}
+			switch a := vm.peek(1).(type) {
+			case float64:
+				if b, ok := vm.peek(0).(float64); ok {
+					result = a + b
+				}
+			case string:
+				if b, ok := vm.peek(0).(string); ok {
+					if err := vm.alloc(); err != nil {
+						if err := fail(err); err != nil {
+							return err
+						}
+						continue
+					}
+					result = a + b
+				}
+			}
+			if result == nil {
+				if err := fail(diag.Errorf(diag.TypeError, "Operands must be both numbers or both strings.")); err != nil {
+					return err
+				}
+				continue
+			}
+			vm.pop()
+			vm.stack[len(vm.stack)-1] = result
+		case opNot:
+			vm.push(!value.Truthy(vm.pop()))
+		case opNegate:
+			n, ok := vm.peek(0).(float64)
+			if !ok {
+				if err := fail(diag.Errorf(diag.TypeError, "Operand must be a number.")); err != nil {
+					return err
+				}
+				continue
+			}
+			vm.stack[len(vm.stack)-1] = -n
+
+		case opPrint:
+			vm.Print(vm.pop())
+
+		case opJump:
+			offset := readShort()
+			fr.ip += offset
+		case opJumpIfFalse:
+			offset := readShort()
+			if !value.Truthy(vm.peek(0)) {
+				fr.ip += offset
+			}
+		case opLoop:
+			offset := readShort()
+			if err := vm.tick(); err != nil {
+				if err := fail(err); err != nil {
+					return err
+				}
+				continue
+			}
+			fr.ip -= offset
+
+		case opCall:
+			argc := int(code[fr.ip])
+			fr.ip++
+			if err := vm.callValue(vm.peek(argc), argc); err != nil {
+				if err := fail(err); err != nil {
+					return err
+				}
+				continue
+			}
+			reload()
+		case opClosure:
+			function := constants[readShort()].(*function)
+			if err := vm.alloc(); err != nil {
+				if err := fail(err); err != nil {
+					return err
+				}
+				continue
+			}
+			closure := &closure{
+				function: function,
+				upvalues: make([]*upvalue, function.upvalueCount),
+				module:   fr.closure.module,
+			}
+			for i := range closure.upvalues {
+				isLocal, index := code[fr.ip], int(code[fr.ip+1])
+				fr.ip += 2
+				if isLocal == 1 {
+					closure.upvalues[i] = vm.captureUpvalue(fr.base + index)
+				} else {
+					closure.upvalues[i] = fr.closure.upvalues[index]
+				}
+			}
+			vm.push(closure)
+		case opCloseUpvalue:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case opReturn:
+			result := vm.pop()
+			vm.closeUpvalues(fr.base)
+			if fr.constructor {
+				result = vm.stack[fr.base]
+			}
+			if fr.module != nil {
+				vm.modules[fr.module.file] = fr.module
+				vm.loading = vm.loading[:len(vm.loading)-1]
+				result = fr.module
+			}
+			vm.stack = vm.stack[:fr.base]
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			if len(vm.frames) == 0 {
+				return nil
+			}
+			vm.push(result)
+			reload()
+
+		case opClass:
+			name := constants[readShort()].(string)
+			vm.push(&class{name: name, methods: make(map[string]*closure)})
+		case opInherit:
+			superclass, ok := vm.peek(1).(*class)
+			if !ok {
+				if err := fail(diag.Errorf(diag.TypeError, "Superclass must be a class.")); err != nil {
+					return err
+				}
+				continue
+			}
+			subclass := vm.pop().(*class)
+			// copy the inherited methods down, the subclass' methods
+			// are added afterwards and override them
+			for name, method := range superclass.methods {
+				subclass.methods[name] = method
+			}
+		case opMethod:
+			name := constants[readShort()].(string)
+			method := vm.pop().(*closure)
+			vm.peek(0).(*class).methods[name] = method
+
+		case opList:
+			count := readShort()
+			if err := vm.alloc(); err != nil {
+				if err := fail(err); err != nil {
+					return err
+				}
+				continue
+			}
+			elements := make([]interface{}, count)
+			copy(elements, vm.stack[len(vm.stack)-count:])
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			vm.push(&value.List{Elements: elements})
+		case opMap:
+			count := readShort()
+			if err := vm.alloc(); err != nil {
+				if err := fail(err); err != nil {
+					return err
+				}
+				continue
+			}
+			entries := vm.stack[len(vm.stack)-2*count:]
+			m := value.NewMap()
+			var err error
+			for i := 0; i < count && err == nil; i++ {
+				err = m.Set(entries[2*i], entries[2*i+1])
+			}
+			if err != nil {
+				if err := fail(err); err != nil {
+					return err
+				}
+				continue
+			}
+			vm.stack = vm.stack[:len(vm.stack)-2*count]
+			vm.push(m)
+		case opIndex:
+			var v interface{}
+			var err error
+			switch object := vm.peek(1).(type) {
+			case *value.List:
+				v, err = object.Get(vm.peek(0))
+			case *value.Map:
+				v, err = object.Get(vm.peek(0))
+			case string:
+				v, err = value.StringIndex(object, vm.peek(0))
+			default:
+				err = diag.Errorf(diag.TypeError, "Only lists, maps and strings can be indexed.")
+			}
+			if err != nil {
+				if err := fail(err); err != nil {
+					return err
+				}
+				continue
+			}
+			vm.pop()
+			vm.stack[len(vm.stack)-1] = v
+		case opSetIndex:
+			var err error
+			switch object := vm.peek(2).(type) {
+			case *value.List:
+				err = object.Set(vm.peek(1), vm.peek(0))
+			case *value.Map:
+				err = object.Set(vm.peek(1), vm.peek(0))
+			case string:
+				err = diag.Errorf(diag.TypeError, "Strings can't be modified.")
+			default:
+				err = diag.Errorf(diag.TypeError, "Only lists and maps can be indexed.")
+			}
+			if err != nil {
+				if err := fail(err); err != nil {
+					return err
+				}
+				continue
+			}
+			v := vm.pop()
+			vm.stack = vm.stack[:len(vm.stack)-2]
+			vm.push(v)
+		case opSlice:
+			var slice interface{}
+			var err error
+			switch object := vm.peek(2).(type) {
+			case *value.List:
+				slice, err = object.Slice(vm.peek(1), vm.peek(0))
+			case string:
+				slice, err = value.StringSlice(object, vm.peek(1), vm.peek(0))
+			default:
+				err = diag.Errorf(diag.TypeError, "Only lists and strings can be sliced.")
+			}
+			if err == nil {
+				err = vm.alloc()
+			}
+			if err != nil {
+				if err := fail(err); err != nil {
+					return err
+				}
+				continue
+			}
+			vm.stack = vm.stack[:len(vm.stack)-3]
+			vm.push(slice)
+
+		case opThrow:
+			span := fr.closure.function.chunk.spans[fr.ip-1]
+			v := vm.pop()
+			if err, ok := v.(*value.Error); ok && err.Line == 0 {
+				err.Line = span.Line
+			}
+			if err := vm.throw(v, vm.raisedAt(span)); err != nil {
+				return err
+			}
+			reload()
+		case opRethrow:
+			at := vm.pop().(*raised)
+			if err := vm.throw(vm.pop(), at); err != nil {
+				return err
+			}
+			reload()
+		case opPushHandler:
+			offset := readShort()
+			vm.handlers = append(vm.handlers, handler{
+				frames:   len(vm.frames),
+				stackTop: len(vm.stack),
+				loading:  len(vm.loading),
+				ip:       fr.ip + offset,
+			})
+		case opPopHandler:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+		case opImport:
+			path := constants[readShort()].(string)
+			name := constants[readShort()].(string)
+			if err := vm.importModule(fr.closure.module, path, name); err != nil {
+				if err := fail(err); err != nil {
+					return err
+				}
+				continue
+			}
+			reload()
+		}
+	}
+}
+
+func (vm *VM) push(v interface{}) {
+	vm.stack = append(vm.stack, v)
+}
+
+func (vm *VM) pop() interface{} {
+	v := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return v
+}
+
+// peek returns the value distance slots down from the top of the stack
+func (vm *VM) peek(distance int) interface{} {
+	return vm.stack[len(vm.stack)-1-distance]
+}
+
+// raised tells where a value was thrown, it is kept on the stack
+// while a finally block runs to rethrow the value from there.
+type raised struct {
+	span  token.Span
+	trace []diag.Frame
+}
+
+// raisedAt returns where a value thrown at the given span is raised
+func (vm *VM) raisedAt(span token.Span) *raised {
+	return &raised{span: span, trace: vm.trace(span)}
+}
+
+// throw unwinds the stack to the innermost handler and pushes
+// the thrown value and where it was raised for the handler's code.
+// it returns the error to report when no handler is left.
+func (vm *VM) throw(v interface{}, at *raised) error {
+	if len(vm.handlers) == 0 {
+		return vm.uncaught(v, at)
+	}
+
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+	vm.closeUpvalues(h.stackTop)
+	vm.frames = vm.frames[:h.frames]
+	vm.loading = vm.loading[:h.loading]
+	vm.stack = vm.stack[:h.stackTop]
+	vm.push(v)
+	vm.push(at)
+	vm.frames[len(vm.frames)-1].ip = h.ip
+	return nil
+}
+
+// uncaught returns the error reporting a value no handler caught
+func (vm *VM) uncaught(v interface{}, at *raised) error {
+	d := diag.New(diag.Runtime, at.span, diag.UncaughtException, fmt.Sprintf("Uncaught exception: %s.", value.Stringify(v)))
+	var causes diag.List
+	if err, ok := v.(*value.Error); ok {
+		d.Message = err.Message
+		if err.Code != "" {
+			d.Code = err.Code
+		}
+		causes = err.Causes
+	}
+	d.Trace = at.trace
+	return append(diag.List{d}, causes...)
+}
+
+// trace returns the calls being executed when an error
+// is raised at the given span, from the innermost to the script
+func (vm *VM) trace(at token.Span) []diag.Frame {
+	trace := make([]diag.Frame, 0, len(vm.frames))
+	for j := len(vm.frames) - 1; j >= 0; j-- {
+		fr := &vm.frames[j]
+		// the frames below the innermost one are at their call instruction
+		if j != len(vm.frames)-1 {
+			at = fr.closure.function.chunk.spans[fr.ip-1]
+		}
+		name := fr.closure.function.traceName()
+		if fr.module != nil {
+			name = "<module " + fr.module.name + ">"
+		}
+		trace = append(trace, diag.Frame{Function: name, File: at.File, Line: at.Line})
+	}
+	return trace
+}
+
+func (vm *VM) getProperty(object interface{}, name string) (interface{}, error) {
+	switch object := object.(type) {
+	case *instance:
+		// properties shadow methods
+		if v, ok := object.fields[name]; ok {
+			return v, nil
+		}
+		// don't allow code to access the `init` function
+		if method, ok := object.klass.methods[name]; ok && name != init_ {
+			return &boundMethod{receiver: object, method: method}, nil
+		}
+	case *module:
+		return object.get(name)
+	case *value.Namespace:
+		if v, ok := object.Property(name); ok {
+			return v, nil
+		}
+		return nil, diag.Errorf(diag.UndefinedProperty, "Undefined property '%s' in module '%s'.", name, object.Name)
+	case *value.Error:
+		if v, ok := object.Property(name); ok {
+			return v, nil
+		}
+	default:
+		return nil, diag.Errorf(diag.TypeError, "Only instances have properties.")
+	}
+	return nil, diag.Errorf(diag.UndefinedProperty, "Undefined property '%s'.", name)
+}
+
+// callValue calls the callee sitting below its argc arguments on the stack
+func (vm *VM) callValue(callee interface{}, argc int) error {
+	switch callee := callee.(type) {
+	case *closure:
+		return vm.call(callee, argc)
+	case *boundMethod:
+		vm.stack[len(vm.stack)-argc-1] = callee.receiver
+		return vm.call(callee.method, argc)
+	case *class:
+		if err := vm.alloc(); err != nil {
+			return err
+		}
+		vm.stack[len(vm.stack)-argc-1] = &instance{klass: callee, fields: make(map[string]interface{})}
+		if initializer, ok := callee.methods[init_]; ok {
+			if err := vm.call(initializer, argc); err != nil {
+				return err
+			}
+			vm.frames[len(vm.frames)-1].constructor = true
+			return nil
+		}
+		if argc != 0 {
+			return diag.Errorf(diag.ArityMismatch, "Expected 0 arguments, but got %d.", argc)
+		}
+		return nil
+	case *value.Native:
+		if argc != callee.Arity {
+			return diag.Errorf(diag.ArityMismatch, "Expected %d arguments, but got %d.", callee.Arity, argc)
+		}
+		args := make([]interface{}, argc)
+		copy(args, vm.stack[len(vm.stack)-argc:])
+		result, err := callee.Fn(args)
+		if err != nil {
+			return err
+		}
+		if callee.Allocates(result) {
+			if err := vm.alloc(); err != nil {
+				return err
+			}
+		}
+		vm.stack = vm.stack[:len(vm.stack)-argc-1]
+		vm.push(result)
+		return nil
+	}
+	return diag.Errorf(diag.NotCallable, "Can only call functions and classes.")
+}
+
+func (vm *VM) call(closure *closure, argc int) error {
+	if argc != closure.function.arity {
+		return diag.Errorf(diag.ArityMismatch, "Expected %d arguments, but got %d.", closure.function.arity, argc)
+	}
+	// the first frame is the script's
+	if len(vm.frames) > 0 {
+		if err := vm.tick(); err != nil {
+			return err
+		}
+	}
+	if len(vm.frames)-1 >= vm.maxDepth() {
+		return diag.Errorf(diag.StackOverflow, "Stack overflow.")
+	}
+	vm.frames = append(vm.frames, frame{closure: closure, base: len(vm.stack) - argc - 1})
+	return nil
+}
+
+func (vm *VM) maxDepth() int {
+	if vm.MaxDepth == 0 {
+		return value.DefaultMaxDepth
+	}
+	return vm.MaxDepth
+}
+
+func (vm *VM) captureUpvalue(slot int) *upvalue {
+	var previous *upvalue
+	upvalue_ := vm.openUpvalues
+	for upvalue_ != nil && upvalue_.slot > slot {
+		previous, upvalue_ = upvalue_, upvalue_.next
+	}
+	if upvalue_ != nil && upvalue_.slot == slot {
+		return upvalue_
+	}
+
+	created := &upvalue{slot: slot, open: true, next: upvalue_}
+	if previous == nil {
+		vm.openUpvalues = created
+	} else {
+		previous.next = created
+	}
+	return created
+}
+
+// closeUpvalues moves the variables captured from the
+// given slot up to the top of the stack into their upvalues
+func (vm *VM) closeUpvalues(from int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= from {
+		upvalue := vm.openUpvalues
+		upvalue.closed = vm.stack[upvalue.slot]
+		upvalue.open = false
+		vm.openUpvalues = upvalue.next
+	}
+}
+
+// importModule pushes the cached module imported from the importer,
+// or a frame executing it when it is imported for the first time.
+func (vm *VM) importModule(importer *module, path, name string) error {
+	if vm.Load == nil {
+		return diag.Errorf(diag.ImportError, "Modules can't be imported, no loader was set up.")
+	}
+
+	abs, ok := value.FindModule(importer.file, path, vm.SearchPath)
+	if !ok {
+		return diag.Errorf(diag.ImportError, "Could not find module '%s'.", path)
+	}
+	if m, ok := vm.modules[abs]; ok {
+		vm.push(m)
+		return nil
+	}
+
+	// the main script is the root of the chain of imports
+	chain := vm.loading
+	if root, err := filepath.Abs(vm.main.file); vm.main.file != "" && err == nil {
+		chain = append([]string{root}, chain...)
+	}
+	for i, loading := range chain {
+		if loading == abs {
+			cycle := append(append([]string{}, chain[i:]...), abs)
+			for j := range cycle {
+				cycle[j] = filepath.Base(cycle[j])
+			}
+			return diag.Errorf(diag.ImportError, "Import cycle detected: %s.", strings.Join(cycle, " -> "))
+		}
+	}
+
+	src, err := os.ReadFile(abs)
+	if err != nil {
+		return diag.Errorf(diag.ImportError, "Could not read module '%s'.", path)
+	}
+	// the diagnostics of the module are reported after the import
+	stmts, err := vm.Load(abs, string(src))
+	if diags, ok := err.(diag.List); ok {
+		return diag.Wrapf(diags, diag.ImportError, "Could not load module '%s'.", path)
+	} else if err != nil {
+		return diag.Errorf(diag.ImportError, "Could not load module '%s': %v", path, err)
+	}
+	compiler := compiler{}
+	function, err := compiler.compile(stmts)
+	if diags, ok := err.(diag.List); ok {
+		return diag.Wrapf(diags, diag.ImportError, "Could not compile module '%s'.", path)
+	} else if err != nil {
+		return diag.Errorf(diag.ImportError, "Could not compile module '%s': %v", path, err)
+	}
+
+	// execute the module in its own global scope
+	m := &module{name: name, file: abs, globals: make(map[string]interface{})}
+	closure := &closure{function: function, module: m}
+	vm.push(closure)
+	if err := vm.call(closure, 0); err != nil {
+		return err
+	}
+	vm.frames[len(vm.frames)-1].module = m
+	vm.loading = append(vm.loading, abs)
+	return nil
+}